@@ -3,12 +3,11 @@ package main
 import (
 	"context"
 	"fmt"
-
-	appsv1 "k8s.io/api/apps/v1"
-	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 
 	"github.com/cheriot/kubenav/pkg/app"
 	"github.com/cheriot/kubenav/pkg/app/relations"
@@ -29,6 +28,9 @@ type GetCommand struct {
 
 func (c *GetCommand) Execute(args []string) error {
 	fmt.Printf("Execute GetCommand %+v %+v %+v\n", globalOptions, c, args)
+	if err := app.LoadCategoriesConfig(); err != nil {
+		return err
+	}
 
 	kc, err := app.NewKubeClusterDefault(context.Background())
 	resourceTables, err := kc.Query(context.Background(), c.Namespace, c.PositionalArgs.Kind)
@@ -40,38 +42,45 @@ func (c *GetCommand) Execute(args []string) error {
 	return nil
 }
 
-type RelationsCommand struct{}
+type RelationsCommand struct {
+	Namespace      string                  `long:"namespace" short:"n" required:"true" description:"Namespace scope for queries"`
+	Format         string                  `long:"format" short:"o" default:"dot" choice:"dot" choice:"mermaid" choice:"json" description:"Graph format"`
+	PositionalArgs RelationsPositionalArgs `positional-args:"true"`
+}
+
+type RelationsPositionalArgs struct {
+	Kind string `positional-arg-name:"kind" description:"kind of the object to graph the neighborhood of. Graphs the namespace when omitted."`
+	Name string `positional-arg-name:"name" description:"name of the object"`
+}
 
 func (c *RelationsCommand) Execute(_ []string) error {
-	// pod := &corev1.Pod{}
-	// pod.Spec.NodeName = "fakeNodeName"
-	// ns := &corev1.Namespace{}
-	rs := &appsv1.ReplicaSet{}
-
-	// ownerReferences:
-	// - apiVersion: apps/v1
-	//   blockOwnerDeletion: true
-	//   controller: true
-	//   kind: Deployment
-	//   name: hello-server
-	//   uid: 456996f4-f01e-4aee-b214-6c7984d786fc
-	rs.GetObjectMeta().SetOwnerReferences([]metav1.OwnerReference{{
-		APIVersion: "apps/v1",
-		Kind:       "Deployment",
-		Name:       "hello-server",
-		UID:        "456996f4-f01e-4aee-b214-6c7984d786fc",
-	}})
-
-	scheme := runtime.NewScheme()
-	corev1.AddToScheme(scheme)
-
-	hors := relations.RelationsList(rs, schema.GroupKind{Group: "apps", Kind: "ReplicaSet"})
-
-	fmt.Printf("Found %d relations", len(hors))
-	for _, hor := range hors {
-		fmt.Printf("%+v", hor)
+	ctx := context.Background()
+	if err := app.LoadRelationsConfig(); err != nil {
+		return err
+	}
+
+	kc, err := app.NewKubeClusterDefault(ctx)
+	if err != nil {
+		return err
+	}
+
+	var graph *relations.Graph
+	if c.PositionalArgs.Kind == "" {
+		graph, err = kc.NamespaceGraph(ctx, c.Namespace)
+	} else if c.PositionalArgs.Name == "" {
+		return fmt.Errorf("expected the name of the %s", c.PositionalArgs.Kind)
+	} else {
+		graph, err = kc.ObjectGraph(ctx, c.Namespace, c.PositionalArgs.Kind, c.PositionalArgs.Name)
+	}
+	if err != nil {
+		return err
 	}
 
+	rendered, err := app.RenderGraph(graph, c.Format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(rendered)
 	return nil
 }
 
@@ -110,6 +119,70 @@ func (c *DescribeCommand) Execute(_ []string) error {
 	return nil
 }
 
+type PortForwardCommand struct {
+	Namespace      string                    `long:"namespace" short:"n" required:"true" description:"Namespace scope for queries"`
+	PositionalArgs PortForwardPositionalArgs `positional-args:"true"`
+}
+
+type PortForwardPositionalArgs struct {
+	Kind  string `positional-arg-name:"kind" required:"true" description:"pod or service"`
+	Name  string `positional-arg-name:"name" required:"true" description:"name of the pod or service"`
+	Ports string `positional-arg-name:"ports" required:"true" description:"[local:]remote port. Local defaults to a free port."`
+}
+
+func (c *PortForwardCommand) Execute(_ []string) error {
+	fmt.Printf("Execute PortForwardCommand\n")
+	localPort, remotePort, err := parsePorts(c.PositionalArgs.Ports)
+	if err != nil {
+		return err
+	}
+
+	kc, err := app.NewKubeClusterDefault(context.Background())
+	if err != nil {
+		return err
+	}
+
+	pf, err := kc.StartPortForward(context.Background(), app.PortForwardRequest{
+		Namespace:  c.Namespace,
+		Kind:       c.PositionalArgs.Kind,
+		Name:       c.PositionalArgs.Name,
+		LocalPort:  localPort,
+		RemotePort: remotePort,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Forwarding localhost:%d -> %s/%s:%d. Ctrl-C to stop.\n", pf.LocalPort, pf.Namespace, pf.Pod, pf.PodPort)
+
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-signalCtx.Done()
+
+	kc.StopAllPortForwards()
+	return nil
+}
+
+func parsePorts(ports string) (uint16, uint16, error) {
+	parts := strings.Split(ports, ":")
+	if len(parts) > 2 {
+		return 0, 0, fmt.Errorf("expected [local:]remote ports, got %s", ports)
+	}
+
+	parsed := make([]uint16, len(parts))
+	for i, p := range parts {
+		port, err := strconv.ParseUint(p, 10, 16)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid port %s: %w", p, err)
+		}
+		parsed[i] = uint16(port)
+	}
+
+	if len(parsed) == 1 {
+		return 0, parsed[0], nil
+	}
+	return parsed[0], parsed[1], nil
+}
+
 type ApplicationOptions struct {
 	Verbose    int    `long:"verbose" short:"v" description:"Debug level [0,4]"`
 	KubeConfig string `long:"kubeconfig" description:"Absolute path to the kubeconfig file"`
@@ -137,12 +210,18 @@ func BuildParser(appOptions *ApplicationOptions) (*flags.Parser, error) {
 		return nil, err
 	}
 
-	relDesc := "Relations of an object."
+	relDesc := "Graph the relations of a namespace or an object's neighborhood."
 	_, err = parser.AddCommand("relations", relDesc, relDesc, &RelationsCommand{})
 	if err != nil {
 		return nil, err
 	}
 
+	portForwardDesc := "Forward a local port to a pod or service."
+	_, err = parser.AddCommand("port-forward", portForwardDesc, portForwardDesc, &PortForwardCommand{})
+	if err != nil {
+		return nil, err
+	}
+
 	parser.CommandHandler = func(commander flags.Commander, args []string) error {
 		fmt.Printf("Set log level here. %+v\n", globalOptions)
 		return commander.Execute(args)
@@ -1,24 +1,41 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+	"time"
 
 	"github.com/cheriot/kubenav/pkg/app"
+	"github.com/cheriot/kubenav/pkg/app/relations"
 
 	log "github.com/sirupsen/logrus"
 
 	echo "github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/net/websocket"
+	rbacv1 "k8s.io/api/rbac/v1"
 )
 
 func main() {
+	if err := app.LoadRelationsConfig(); err != nil {
+		log.Errorf("error loading relations config: %v", err)
+	}
+	if err := app.LoadCategoriesConfig(); err != nil {
+		log.Errorf("error loading categories config: %v", err)
+	}
+
 	e := echo.New()
 	e.Use(middleware.Logger())
 
 	e.GET("/api/contexts", func(c echo.Context) error {
 		ctxNames, err := app.KubeContextList()
 		if err != nil {
-			log.Errorf("error from KubeContextList: %w", err)
+			log.Errorf("error from KubeContextList: %v", err)
 		}
 		return c.JSON(http.StatusOK, ctxNames)
 	})
@@ -29,12 +46,12 @@ func main() {
 
 		kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
 		if err != nil {
-			log.Errorf("error getting kubecluster for %s: %w", ctxParam, err)
+			log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
 		}
 
 		nsNames, err := kc.KubeNamespaceList(ctx)
 		if err != nil {
-			log.Errorf("error from KubeNamespaceList: %w", err)
+			log.Errorf("error from KubeNamespaceList: %v", err)
 		}
 		return c.JSON(http.StatusOK, nsNames)
 	})
@@ -47,16 +64,467 @@ func main() {
 
 		kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
 		if err != nil {
-			log.Errorf("error getting kubecluster for %s: %w", ctxParam, err)
+			log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
+		}
+
+		// Prepopulated by a relations.HasManyDestination
+		params := map[string]string{
+			relations.LabelSelectorParam: c.QueryParam(relations.LabelSelectorParam),
+			relations.FieldSelectorParam: c.QueryParam(relations.FieldSelectorParam),
 		}
 
-		resourceTables, err := kc.Query(ctx, nsParam, queryParam)
+		resourceTables, err := kc.QueryWithParams(ctx, nsParam, queryParam, params)
 		if err != nil {
-			log.Errorf("error query %s for %s: %w", queryParam, ctxParam, err)
+			log.Errorf("error query %s for %s: %v", queryParam, ctxParam, err)
 		}
 
 		return c.JSON(http.StatusOK, resourceTables)
 	})
 
-	e.Logger.Fatal(e.Start(":4000"))
+	e.GET("/api/context/:ctx/namespace/:ns/kind/:kind/name/:name/tree", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		ctxParam := c.Param("ctx")
+
+		kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
+		if err != nil {
+			log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		tree, err := kc.OwnershipTree(ctx, c.Param("ns"), c.Param("kind"), c.Param("name"))
+		if err != nil {
+			log.Errorf("error building ownership tree: %v", err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		return c.JSON(http.StatusOK, tree)
+	})
+
+	// format is json (default), dot or mermaid
+	e.GET("/api/context/:ctx/namespace/:ns/graph", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		ctxParam := c.Param("ctx")
+
+		kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
+		if err != nil {
+			log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		graph, err := kc.NamespaceGraph(ctx, c.Param("ns"))
+		if err != nil {
+			log.Errorf("error building namespace graph: %v", err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		return renderGraph(c, graph)
+	})
+
+	e.GET("/api/context/:ctx/namespace/:ns/kind/:kind/name/:name/graph", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		ctxParam := c.Param("ctx")
+
+		kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
+		if err != nil {
+			log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		graph, err := kc.ObjectGraph(ctx, c.Param("ns"), c.Param("kind"), c.Param("name"))
+		if err != nil {
+			log.Errorf("error building object graph: %v", err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		return renderGraph(c, graph)
+	})
+
+	e.GET("/api/context/:ctx/namespace/:ns/dangling", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		ctxParam := c.Param("ctx")
+
+		kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
+		if err != nil {
+			log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		return c.JSON(http.StatusOK, kc.DanglingReferences(ctx, c.Param("ns")))
+	})
+
+	e.GET("/api/context/:ctx/overview", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		ctxParam := c.Param("ctx")
+
+		kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
+		if err != nil {
+			log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		overview, err := kc.ClusterOverview(ctx)
+		if err != nil {
+			log.Errorf("error building cluster overview: %v", err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		return c.JSON(http.StatusOK, overview)
+	})
+
+	e.GET("/api/context/:ctx/namespace/:ns/overview", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		ctxParam := c.Param("ctx")
+
+		kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
+		if err != nil {
+			log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		overview, err := kc.NamespaceOverview(ctx, c.Param("ns"))
+		if err != nil {
+			log.Errorf("error building namespace overview: %v", err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		return c.JSON(http.StatusOK, overview)
+	})
+
+	e.GET("/api/context/:ctx/namespace/:ns/ingress/:name/traffic", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		ctxParam := c.Param("ctx")
+
+		kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
+		if err != nil {
+			log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		trafficPath, err := kc.TrafficPath(ctx, c.Param("ns"), c.Param("name"))
+		if err != nil {
+			log.Errorf("error building traffic path: %v", err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		return c.JSON(http.StatusOK, trafficPath)
+	})
+
+	e.GET("/api/context/:ctx/namespace/:ns/pod/:pod/networkaccess", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		ctxParam := c.Param("ctx")
+
+		kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
+		if err != nil {
+			log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		access, err := kc.NetworkAccess(ctx, c.Param("ns"), c.Param("pod"))
+		if err != nil {
+			log.Errorf("error summarizing network access: %v", err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		return c.JSON(http.StatusOK, access)
+	})
+
+	// subjectKind is ServiceAccount, User or Group. ServiceAccounts also need the namespace query param.
+	e.GET("/api/context/:ctx/permissions/:subjectKind/:name", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		ctxParam := c.Param("ctx")
+
+		kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
+		if err != nil {
+			log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		subject := rbacv1.Subject{
+			Kind:      c.Param("subjectKind"),
+			Name:      c.Param("name"),
+			Namespace: c.QueryParam("namespace"),
+		}
+		permissions, err := kc.Permissions(ctx, subject)
+		if err != nil {
+			log.Errorf("error finding permissions of %+v: %v", subject, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		return c.JSON(http.StatusOK, permissions)
+	})
+
+	e.GET("/api/compare/:leftCtx/:rightCtx/namespace/:ns/query/:query", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		leftCtxParam := c.Param("leftCtx")
+		rightCtxParam := c.Param("rightCtx")
+		nsParam := c.Param("ns")
+		queryParam := c.Param("query")
+
+		comparison, err := app.CompareNamespace(ctx, leftCtxParam, rightCtxParam, nsParam, queryParam)
+		if err != nil {
+			log.Errorf("error comparing %s between %s and %s: %v", nsParam, leftCtxParam, rightCtxParam, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		return c.JSON(http.StatusOK, comparison)
+	})
+
+	e.GET("/api/context/:ctx/namespace/:ns/pod/:pod/exec", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		ctxParam := c.Param("ctx")
+
+		kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
+		if err != nil {
+			log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		opts := app.ExecOptions{
+			Namespace: c.Param("ns"),
+			Pod:       c.Param("pod"),
+			Container: c.QueryParam("container"),
+			Command:   c.QueryParams()["command"],
+			TTY:       c.QueryParam("tty") != "false",
+		}
+
+		server := localWebsocket(func(ws *websocket.Conn) {
+			defer ws.Close()
+			conn := &wsTerminalConn{ws: ws}
+
+			exit := app.TerminalMessage{Op: app.TerminalOpExit}
+			err := kc.Exec(ctx, opts, conn)
+			if err != nil {
+				log.Errorf("error exec %+v: %v", opts, err)
+				exit.Data = err.Error()
+			}
+			conn.Send(exit)
+		})
+		server.ServeHTTP(c.Response(), c.Request())
+		return nil
+	})
+
+	// Adds an ephemeral container. Attach to it with the exec websocket.
+	e.POST("/api/context/:ctx/namespace/:ns/pod/:pod/debug", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		ctxParam := c.Param("ctx")
+
+		// A form on another site can post here, so this changes the cluster only for the app's own origin
+		if origin := c.Request().Header.Get("Origin"); !isAllowedOrigin(origin) {
+			return c.JSON(http.StatusForbidden, app.ErrorCommandResult(fmt.Sprintf("origin %s is not allowed", origin)))
+		}
+
+		opts := app.DebugOptions{}
+		if err := c.Bind(&opts); err != nil {
+			return c.JSON(http.StatusBadRequest, app.ErrorCommandResult(err.Error()))
+		}
+		opts.Namespace = c.Param("ns")
+		opts.Pod = c.Param("pod")
+
+		kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
+		if err != nil {
+			log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		container, err := kc.DebugPod(ctx, opts)
+		if err != nil {
+			log.Errorf("error debugging %+v: %v", opts, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		return c.JSON(http.StatusOK, app.DebugContainer{Namespace: opts.Namespace, Pod: opts.Pod, Container: container})
+	})
+
+	e.GET("/api/context/:ctx/portforwards", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		ctxParam := c.Param("ctx")
+
+		kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
+		if err != nil {
+			log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		return c.JSON(http.StatusOK, kc.ListPortForwards())
+	})
+
+	e.POST("/api/context/:ctx/namespace/:ns/portforwards", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		ctxParam := c.Param("ctx")
+
+		req := app.PortForwardRequest{}
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, app.ErrorCommandResult(err.Error()))
+		}
+		req.Namespace = c.Param("ns")
+
+		kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
+		if err != nil {
+			log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		// The forward outlives this request. ctx only bounds starting it.
+		pf, err := kc.StartPortForward(ctx, req)
+		if err != nil {
+			log.Errorf("error starting port-forward %+v: %v", req, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		return c.JSON(http.StatusOK, pf)
+	})
+
+	e.DELETE("/api/context/:ctx/portforwards/:id", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		ctxParam := c.Param("ctx")
+
+		kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
+		if err != nil {
+			log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		err = kc.StopPortForward(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusNotFound, app.ErrorCommandResult(err.Error()))
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	e.GET("/api/context/:ctx/namespace/:ns/pod/:pod/download", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		ctxParam := c.Param("ctx")
+
+		kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
+		if err != nil {
+			log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
+			return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+		}
+
+		opts := app.CopyOptions{
+			Namespace: c.Param("ns"),
+			Pod:       c.Param("pod"),
+			Container: c.QueryParam("container"),
+			Path:      c.QueryParam("path"),
+		}
+
+		w := &attachmentWriter{response: c.Response(), contentType: "application/x-tar", fileName: path.Base(opts.Path) + ".tar"}
+		err = kc.CopyFromContainer(opts, w)
+		if err != nil {
+			log.Errorf("error downloading %+v: %v", opts, err)
+			if !c.Response().Committed {
+				return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+			}
+		}
+		return nil
+	})
+
+	e.POST("/api/context/:ctx/namespace/:ns/pod/:pod/upload", uploadFile)
+
+	go func() {
+		if err := e.Start(listenAddr); err != nil && err != http.ErrServerClosed {
+			e.Logger.Fatal(err)
+		}
+	}()
+
+	// Port-forwards live as long as the server process
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-signalCtx.Done()
+
+	app.StopAllPortForwards()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		e.Logger.Fatal(err)
+	}
+}
+
+// uploadFile copies the multipart form's file into a container's directory.
+func uploadFile(c echo.Context) error {
+	ctx := c.Request().Context()
+	ctxParam := c.Param("ctx")
+
+	// A no-cors fetch from another site can post a multipart form here
+	if origin := c.Request().Header.Get("Origin"); !isAllowedOrigin(origin) {
+		return c.JSON(http.StatusForbidden, app.ErrorCommandResult(fmt.Sprintf("origin %s is not allowed", origin)))
+	}
+
+	kc, err := app.GetOrMakeKubeCluster(ctx, ctxParam)
+	if err != nil {
+		log.Errorf("error getting kubecluster for %s: %v", ctxParam, err)
+		return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, app.ErrorCommandResult(err.Error()))
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, app.ErrorCommandResult(err.Error()))
+	}
+	defer file.Close()
+
+	opts := app.CopyOptions{
+		Namespace: c.Param("ns"),
+		Pod:       c.Param("pod"),
+		Container: c.QueryParam("container"),
+		Path:      c.QueryParam("path"),
+	}
+	err = kc.CopyToContainer(ctx, opts, path.Base(fileHeader.Filename), fileHeader.Size, file)
+	if err != nil {
+		log.Errorf("error uploading %s to %+v: %v", fileHeader.Filename, opts, err)
+		return c.JSON(http.StatusInternalServerError, app.ErrorCommandResult(err.Error()))
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// wsTerminalConn sends app.TerminalMessages as json websocket frames.
+type wsTerminalConn struct {
+	ws *websocket.Conn
+}
+
+func (w *wsTerminalConn) Receive() (app.TerminalMessage, error) {
+	var msg app.TerminalMessage
+	err := websocket.JSON.Receive(w.ws, &msg)
+	return msg, err
+}
+
+func (w *wsTerminalConn) Send(msg app.TerminalMessage) error {
+	return websocket.JSON.Send(w.ws, msg)
+}
+
+func (w *wsTerminalConn) Close() error {
+	return w.ws.Close()
+}
+
+// attachmentWriter sets the download headers on the first write so a failure before then can still send a json error.
+type attachmentWriter struct {
+	response    *echo.Response
+	contentType string
+	fileName    string
+}
+
+func (w *attachmentWriter) Write(p []byte) (int, error) {
+	if !w.response.Committed {
+		w.response.Header().Set(echo.HeaderContentType, w.contentType)
+		w.response.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", w.fileName))
+	}
+	return w.response.Write(p)
+}
+
+func renderGraph(c echo.Context, graph *relations.Graph) error {
+	format := c.QueryParam("format")
+	if format == "" || format == relations.GraphJSON {
+		return c.JSON(http.StatusOK, graph)
+	}
+
+	rendered, err := app.RenderGraph(graph, format)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, app.ErrorCommandResult(err.Error()))
+	}
+	return c.String(http.StatusOK, rendered)
 }
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	echo "github.com/labstack/echo/v4"
+)
+
+func TestUploadFileRejectsOtherOrigins(t *testing.T) {
+	// What a no-cors fetch from another site sends
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "authorized_keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte("ssh-ed25519 AAAA attacker"))
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/context/prod/namespace/back-end/pod/product-a/upload?path=/root/.ssh", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Origin", "https://example.com")
+	rec := httptest.NewRecorder()
+
+	e := echo.New()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("ctx", "ns", "pod")
+	c.SetParamValues("prod", "back-end", "product-a")
+	if err := uploadFile(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected %d, got %d: %s", http.StatusForbidden, rec.Code, rec.Body.String())
+	}
+}
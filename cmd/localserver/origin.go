@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/websocket"
+)
+
+// Only listen on loopback. The api can exec into pods, so it must not be reachable from the network.
+const listenAddr = "127.0.0.1:4000"
+
+// The browser app is served from the server itself. Requests from any other origin, ie another site open in the
+// same browser, are rejected.
+var allowedOrigins = map[string]bool{
+	"http://localhost:4000": true,
+	"http://127.0.0.1:4000": true,
+}
+
+// isAllowedOrigin of a request's Origin header. Browsers always send one for websockets and cross site posts, so a
+// missing header is a local, non-browser client.
+func isAllowedOrigin(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return allowedOrigins[u.Scheme+"://"+u.Host]
+}
+
+// localWebsocket is websocket.Handler with an Origin check. websocket.Handler accepts every origin.
+func localWebsocket(handler func(*websocket.Conn)) websocket.Server {
+	return websocket.Server{
+		Handler: handler,
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			origin := req.Header.Get("Origin")
+			if !isAllowedOrigin(origin) {
+				return fmt.Errorf("websocket from origin %s is not allowed", origin)
+			}
+			if origin != "" {
+				u, err := url.Parse(origin)
+				if err != nil {
+					return err
+				}
+				config.Origin = u
+			}
+			return nil
+		},
+	}
+}
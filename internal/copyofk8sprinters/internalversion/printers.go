@@ -1704,6 +1704,11 @@ func findNodeRoles(node *api.Node) []string {
 	return roles.List()
 }
 
+// FindNodeRoles is findNodeRoles for use outside the printers.
+func FindNodeRoles(node *api.Node) []string {
+	return findNodeRoles(node)
+}
+
 func printNodeList(list *api.NodeList, options printers.GenerateOptions) ([]metav1.TableRow, error) {
 	rows := make([]metav1.TableRow, 0, len(list.Items))
 	for i := range list.Items {
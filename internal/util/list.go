@@ -1,5 +1,7 @@
 package util
 
+import "sync"
+
 func Map[A any, B any](as []A, f func(A) B) []B {
 	bs := make([]B, len(as))
 	for i, a := range as {
@@ -41,3 +43,30 @@ func Partition[A any](as []A, f func(A) bool) ([]A, []A) {
 
 	return yes, no
 }
+
+// MapConcurrent is Map with at most workers calls to f running at once. The results are in the order of as.
+func MapConcurrent[A any, B any](as []A, workers int, f func(A) B) []B {
+	bs := make([]B, len(as))
+	if workers < 1 {
+		workers = 1
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(as); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				bs[i] = f(as[i])
+			}
+		}()
+	}
+	for i := range as {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return bs
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMapConcurrent(t *testing.T) {
+	var running, most int32
+	double := func(a int) int {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&most)
+			if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+				break
+			}
+		}
+		// Later items finish first
+		time.Sleep(time.Duration(10-a) * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return a * 2
+	}
+
+	actual := MapConcurrent([]int{1, 2, 3, 4, 5, 6, 7, 8, 9}, 3, double)
+	expected := []int{2, 4, 6, 8, 10, 12, 14, 16, 18}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	if most > 3 {
+		t.Errorf("expected at most 3 concurrent calls, got %d", most)
+	}
+
+	if empty := MapConcurrent([]int{}, 3, double); len(empty) != 0 {
+		t.Errorf("expected no results, got %v", empty)
+	}
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// KubeObjectAction is something the object page can offer to do with the object.
+type KubeObjectAction struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Parameters the action accepts with their default values
+	Params map[string]string `json:"params"`
+}
+
+func objectActions(gk schema.GroupKind, obj *unstructured.Unstructured) []KubeObjectAction {
+	actions := make([]KubeObjectAction, 0)
+	if gk == (schema.GroupKind{Kind: "Pod"}) {
+		actions = append(actions, KubeObjectAction{
+			Name:        "debug",
+			Description: "Start an ephemeral container in the pod and open a shell in it.",
+			// Named like DebugOptions' json so a client can post them back
+			Params: map[string]string{
+				"image":           DefaultDebugImage,
+				"targetContainer": firstContainerName(obj),
+			},
+		})
+	}
+	return actions
+}
+
+func firstContainerName(pod *unstructured.Unstructured) string {
+	containers, _, _ := unstructured.NestedSlice(pod.Object, "spec", "containers")
+	if len(containers) == 0 {
+		return ""
+	}
+	if c, ok := containers[0].(map[string]interface{}); ok {
+		name, _, _ := unstructured.NestedString(c, "name")
+		return name
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	echo "github.com/labstack/echo/v4"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestDebugActionParamsBind(t *testing.T) {
+	pod := readyPod("product-a", nil)
+	pod.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := objectActions(podGK, &unstructured.Unstructured{Object: content})
+	if len(actions) != 1 || actions[0].Name != "debug" {
+		t.Fatalf("expected the debug action, got %+v", actions)
+	}
+
+	// The client posts the params back as the body of the debug request
+	body, err := json.Marshal(actions[0].Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/debug", bytes.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+
+	opts := DebugOptions{}
+	if err := c.Bind(&opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.Image != DefaultDebugImage || opts.TargetContainer != pod.Spec.Containers[0].Name {
+		t.Errorf("expected the action's image and target container, got %+v", opts)
+	}
+}
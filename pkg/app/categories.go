@@ -0,0 +1,220 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	util "github.com/cheriot/kubenav/internal/util"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Categories expand a query to a curated list of kinds instead of every resource that claims the category. Users
+// add their own, or add to the built in ones, in a config file. ie
+//
+//	categories:
+//	- name: workloads
+//	  kinds: [{group: argoproj.io, kind: Rollout}]
+//	- name: certs
+//	  kinds: [{group: cert-manager.io, kind: Certificate}, {group: cert-manager.io, kind: Issuer}]
+//	exclude:
+//	- {group: metrics.k8s.io, kind: PodMetrics}
+//
+// Kinds the cluster doesn't serve are skipped.
+
+type CategoriesConfig struct {
+	Categories []Category `yaml:"categories"`
+	// Left out of every category, including the ones the api server defines
+	Exclude []CategoryKind `yaml:"exclude"`
+}
+
+type Category struct {
+	Name string `yaml:"name"`
+	// In the order they're queried
+	Kinds   []CategoryKind `yaml:"kinds"`
+	Exclude []CategoryKind `yaml:"exclude"`
+	// Leave out cluster scoped kinds, ie for an inventory of a namespace
+	NamespacedOnly bool `yaml:"namespacedOnly"`
+}
+
+// CategoryKind is a kind of the core group when Group is empty.
+type CategoryKind struct {
+	Group string `yaml:"group"`
+	Kind  string `yaml:"kind"`
+}
+
+func (ck CategoryKind) GroupKind() schema.GroupKind {
+	return schema.GroupKind{Group: ck.Group, Kind: ck.Kind}
+}
+
+var (
+	workloadKinds = []CategoryKind{
+		{Group: "apps", Kind: "Deployment"},
+		{Group: "apps", Kind: "StatefulSet"},
+		{Group: "apps", Kind: "DaemonSet"},
+		{Group: "apps", Kind: "ReplicaSet"},
+		{Group: "batch", Kind: "CronJob"},
+		{Group: "batch", Kind: "Job"},
+		{Kind: "Pod"},
+		{Group: "autoscaling", Kind: "HorizontalPodAutoscaler"},
+		{Group: "policy", Kind: "PodDisruptionBudget"},
+	}
+	networkKinds = []CategoryKind{
+		{Kind: "Service"},
+		{Group: "networking.k8s.io", Kind: "Ingress"},
+		{Group: "networking.k8s.io", Kind: "NetworkPolicy"},
+		{Group: "discovery.k8s.io", Kind: "EndpointSlice"},
+		{Group: "networking.k8s.io", Kind: "IngressClass"},
+	}
+	configKinds = []CategoryKind{
+		{Kind: "ConfigMap"},
+		{Kind: "Secret"},
+		{Kind: "ResourceQuota"},
+		{Kind: "LimitRange"},
+	}
+	storageKinds = []CategoryKind{
+		{Kind: "PersistentVolumeClaim"},
+		{Kind: "PersistentVolume"},
+		{Group: "storage.k8s.io", Kind: "StorageClass"},
+	}
+	rbacKinds = []CategoryKind{
+		{Kind: "ServiceAccount"},
+		{Group: "rbac.authorization.k8s.io", Kind: "Role"},
+		{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"},
+		{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"},
+		{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"},
+	}
+)
+
+func builtinCategories() map[string]*Category {
+	all := make([]CategoryKind, 0)
+	for _, kinds := range [][]CategoryKind{workloadKinds, networkKinds, configKinds, storageKinds, rbacKinds} {
+		all = append(all, kinds...)
+	}
+	return map[string]*Category{
+		"workloads": {Name: "workloads", Kinds: workloadKinds},
+		"network":   {Name: "network", Kinds: networkKinds},
+		"config":    {Name: "config", Kinds: configKinds},
+		"storage":   {Name: "storage", Kinds: storageKinds},
+		"rbac":      {Name: "rbac", Kinds: rbacKinds},
+		// Everything in a namespace worth looking at, plus whatever else claims the api server's `all`
+		"all": {Name: "all", Kinds: all, NamespacedOnly: true},
+	}
+}
+
+var categories = builtinCategories()
+var excludedKinds = make([]CategoryKind, 0)
+
+// LoadCategories reads and registers the categories of a config file. A missing file isn't an error.
+func LoadCategories(path string) error {
+	bytes, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read categories config %s: %w", path, err)
+	}
+
+	config, err := ParseCategories(bytes)
+	if err != nil {
+		return fmt.Errorf("unable to parse categories config %s: %w", path, err)
+	}
+	RegisterCategories(config)
+	return nil
+}
+
+func ParseCategories(bytes []byte) (*CategoriesConfig, error) {
+	config := &CategoriesConfig{}
+	if err := yaml.Unmarshal(bytes, config); err != nil {
+		return nil, err
+	}
+
+	for i, c := range config.Categories {
+		if c.Name == "" {
+			return nil, fmt.Errorf("category %d: name is required", i)
+		}
+		for _, kinds := range [][]CategoryKind{c.Kinds, c.Exclude} {
+			for _, ck := range kinds {
+				if ck.Kind == "" {
+					return nil, fmt.Errorf("category %s: kind is required", c.Name)
+				}
+			}
+		}
+	}
+	for _, ck := range config.Exclude {
+		if ck.Kind == "" {
+			return nil, fmt.Errorf("exclude: kind is required")
+		}
+	}
+	return config, nil
+}
+
+// RegisterCategories adds new categories and adds to existing ones. Call it before serving requests.
+func RegisterCategories(config *CategoriesConfig) {
+	for _, c := range config.Categories {
+		name := strings.ToLower(c.Name)
+		existing, found := categories[name]
+		if !found {
+			c := c
+			categories[name] = &c
+			continue
+		}
+		existing.Kinds = append(append([]CategoryKind{}, existing.Kinds...), c.Kinds...)
+		existing.Exclude = append(append([]CategoryKind{}, existing.Exclude...), c.Exclude...)
+		existing.NamespacedOnly = existing.NamespacedOnly || c.NamespacedOnly
+	}
+	excludedKinds = append(excludedKinds, config.Exclude...)
+}
+
+// expandCategory lists the category's kinds in order, then the other resources that claim it. Returns false if
+// identifier isn't a category of kubenav or the api server.
+func expandCategory(apiResources []metav1.APIResource, identifier string) ([]metav1.APIResource, bool) {
+	name := strings.ToLower(identifier)
+	claiming := util.Filter(apiResources, func(r metav1.APIResource) bool {
+		return util.Contains(r.Categories, name)
+	})
+	category, found := categories[name]
+	if !found && len(claiming) == 0 {
+		return nil, false
+	}
+	if !found {
+		category = &Category{Name: name}
+	}
+
+	expanded := make([]metav1.APIResource, 0)
+	seen := make(map[schema.GroupKind]bool)
+	add := func(r metav1.APIResource) {
+		gk := toGK(r)
+		if seen[gk] || isExcluded(gk, excludedKinds) || isExcluded(gk, category.Exclude) {
+			return
+		}
+		if category.NamespacedOnly && !r.Namespaced {
+			return
+		}
+		seen[gk] = true
+		expanded = append(expanded, r)
+	}
+
+	for _, ck := range category.Kinds {
+		if r, found := findAPIResourceByGK(apiResources, ck.GroupKind()); found {
+			add(r)
+		}
+	}
+	for _, r := range claiming {
+		add(r)
+	}
+	return expanded, true
+}
+
+func isExcluded(gk schema.GroupKind, excluded []CategoryKind) bool {
+	for _, ck := range excluded {
+		if ck.GroupKind() == gk {
+			return true
+		}
+	}
+	return false
+}
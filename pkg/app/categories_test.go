@@ -0,0 +1,80 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	util "github.com/cheriot/kubenav/internal/util"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFindAPIResourcesByCategory(t *testing.T) {
+	defer func() {
+		categories = builtinCategories()
+		excludedKinds = make([]CategoryKind, 0)
+	}()
+
+	apiResources := []metav1.APIResource{
+		{Kind: "Pod", Name: "pods", Namespaced: true, ShortNames: []string{"po"}, Categories: []string{"all"}},
+		{Kind: "Event", Name: "events", Namespaced: true},
+		{Kind: "PersistentVolume", Name: "persistentvolumes"},
+		{Kind: "ConfigMap", Name: "configmaps", Namespaced: true},
+		{Group: "apps", Kind: "Deployment", Name: "deployments", Namespaced: true, Categories: []string{"all"}},
+		{Group: "argoproj.io", Kind: "Rollout", Name: "rollouts", Namespaced: true, Categories: []string{"all", "argo"}},
+		{Group: "argoproj.io", Kind: "AnalysisRun", Name: "analysisruns", Namespaced: true, Categories: []string{"all", "argo"}},
+	}
+	kinds := func(query string) []string {
+		return util.Map(findAPIResources(apiResources, query), func(r metav1.APIResource) string { return r.Kind })
+	}
+
+	cases := []struct {
+		query    string
+		expected []string
+	}{
+		// Curated kinds in order, then the others that claim `all`. Cluster scoped PersistentVolumes are left out.
+		{"all", []string{"Deployment", "Pod", "ConfigMap", "Rollout", "AnalysisRun"}},
+		{"storage", []string{"PersistentVolume"}},
+		{"argo", []string{"Rollout", "AnalysisRun"}},
+		{"po", []string{"Pod"}},
+	}
+	for _, c := range cases {
+		if actual := kinds(c.query); !reflect.DeepEqual(c.expected, actual) {
+			t.Errorf("%s: expected %v, got %v", c.query, c.expected, actual)
+		}
+	}
+
+	config, err := ParseCategories([]byte(`
+categories:
+- name: workloads
+  kinds: [{group: argoproj.io, kind: Rollout}]
+- name: Inventory
+  kinds: [{kind: ConfigMap}, {kind: Event}]
+  exclude: [{kind: Event}]
+exclude:
+- {group: argoproj.io, kind: AnalysisRun}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterCategories(config)
+
+	cases = []struct {
+		query    string
+		expected []string
+	}{
+		{"workloads", []string{"Deployment", "Pod", "Rollout"}},
+		{"inventory", []string{"ConfigMap"}},
+		{"all", []string{"Deployment", "Pod", "ConfigMap", "Rollout"}},
+		{"argo", []string{"Rollout"}},
+	}
+	for _, c := range cases {
+		if actual := kinds(c.query); !reflect.DeepEqual(c.expected, actual) {
+			t.Errorf("%s: expected %v, got %v", c.query, c.expected, actual)
+		}
+	}
+
+	if _, err := ParseCategories([]byte("categories:\n- kinds: [{kind: Pod}]\n")); err == nil {
+		t.Errorf("expected an error for a category without a name")
+	}
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cheriot/kubenav/pkg/app/relations"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// findChildren lists the kinds obj likely owns in its namespace and keeps the objects with an ownerReference to it.
+// Only cluster scoped kinds are searched for the children of a cluster scoped obj.
+func (kc *KubeCluster) findChildren(ctx context.Context, obj *unstructured.Unstructured, gk schema.GroupKind) ([]relations.HasOneDestination, []error) {
+	children, errors := kc.listChildren(ctx, obj, gk, nil)
+
+	candidates := make([]runtime.Object, len(children))
+	for i := range children {
+		candidates[i] = children[i].obj
+	}
+	return relations.ChildList(obj, candidates), errors
+}
+
+type childObject struct {
+	apiResource metav1.APIResource
+	obj         *unstructured.Unstructured
+}
+
+type childListKey struct {
+	gk        schema.GroupKind
+	namespace string
+}
+
+type childList struct {
+	uList *unstructured.UnstructuredList
+	err   error
+}
+
+// childListCache lets the nodes of a tree share the lists of their child kinds. A nil cache lists every time.
+type childListCache map[childListKey]childList
+
+func (kc *KubeCluster) listChildren(ctx context.Context, obj *unstructured.Unstructured, gk schema.GroupKind, cache childListCache) ([]childObject, []error) {
+	children := make([]childObject, 0)
+	errors := make([]error, 0)
+
+	isCustomResource := !kc.scheme.IsGroupRegistered(gk.Group)
+	for _, childGK := range relations.ChildKinds(gk, isCustomResource) {
+		apiResource, found := findAPIResourceByGK(kc.apiResources, childGK)
+		if !found {
+			continue
+		}
+		// Its namespaced children could be in any namespace. Listing all of them, ie every secret in the cluster,
+		// costs too much for a page about one object.
+		if obj.GetNamespace() == "" && apiResource.Namespaced {
+			continue
+		}
+
+		uList, err := kc.listChildKind(ctx, apiResource, obj.GetNamespace(), cache)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("unable to list %s children of %s: %w", childGK, obj.GetName(), err))
+			continue
+		}
+		if isTruncated(uList) {
+			errors = append(errors, fmt.Errorf("only the first %d %s were searched for children of %s", LIST_LIMIT, childGK, obj.GetName()))
+		}
+
+		for i := range uList.Items {
+			if relations.OwnerReferenceTo(&uList.Items[i], obj.GetUID()) != nil {
+				children = append(children, childObject{apiResource: apiResource, obj: &uList.Items[i]})
+			}
+		}
+	}
+
+	return children, errors
+}
+
+func (kc *KubeCluster) listChildKind(ctx context.Context, r metav1.APIResource, namespace string, cache childListCache) (*unstructured.UnstructuredList, error) {
+	key := childListKey{gk: toGK(r), namespace: namespace}
+	if cached, found := cache[key]; found {
+		return cached.uList, cached.err
+	}
+
+	uList, err := kc.listUnstructured(ctx, r, namespace, metav1.ListOptions{})
+	if cache != nil {
+		cache[key] = childList{uList: uList, err: err}
+	}
+	return uList, err
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+var fakeAPIResources = []metav1.APIResource{
+	{Name: "pods", Namespaced: true, Version: "v1", Kind: "Pod", Verbs: []string{"get", "list"}},
+	{Name: "configmaps", Namespaced: true, Version: "v1", Kind: "ConfigMap", Verbs: []string{"get", "list"}},
+	{Name: "secrets", Namespaced: true, Version: "v1", Kind: "Secret", Verbs: []string{"get", "list"}},
+	{Name: "deployments", Namespaced: true, Group: "apps", Version: "v1", Kind: "Deployment", Verbs: []string{"get", "list"}},
+	{Name: "replicasets", Namespaced: true, Group: "apps", Version: "v1", Kind: "ReplicaSet", Verbs: []string{"get", "list"}},
+	{Name: "tenants", Namespaced: false, Group: "example.com", Version: "v1", Kind: "Tenant", Verbs: []string{"get", "list"}},
+	{Name: "databases", Namespaced: true, Group: "example.com", Version: "v1", Kind: "Database", Verbs: []string{"get", "list"}},
+}
+
+// fakeCluster serves objs from a fake dynamic client for the kinds of fakeAPIResources.
+func fakeCluster(t *testing.T, objs ...runtime.Object) (*KubeCluster, *dynamicfake.FakeDynamicClient) {
+	newScheme := func() *runtime.Scheme {
+		scheme := runtime.NewScheme()
+		if err := schemeBuilder.AddToScheme(scheme); err != nil {
+			t.Fatal(err)
+		}
+		return scheme
+	}
+	listKinds := make(map[schema.GroupVersionResource]string)
+	for _, r := range fakeAPIResources {
+		listKinds[toGVR(r)] = r.Kind + "List"
+	}
+	// The fake registers the custom resources in its scheme, which would make them look built in to kc
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(newScheme(), listKinds, objs...)
+	return &KubeCluster{name: "test", apiResources: fakeAPIResources, scheme: newScheme(), dynamicClient: client}, client
+}
+
+// truncatingClient reports more objects after every list of resource, like a list that stopped at LIST_LIMIT. The fake
+// dynamic client drops the continue token.
+type truncatingClient struct {
+	dynamic.Interface
+	resource string
+}
+
+func (c truncatingClient) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	ri := c.Interface.Resource(gvr)
+	if gvr.Resource != c.resource {
+		return ri
+	}
+	return truncatingResource{NamespaceableResourceInterface: ri}
+}
+
+type truncatingResource struct {
+	dynamic.NamespaceableResourceInterface
+}
+
+func (r truncatingResource) Namespace(ns string) dynamic.ResourceInterface {
+	return truncatingList{ResourceInterface: r.NamespaceableResourceInterface.Namespace(ns)}
+}
+
+func (r truncatingResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	return truncatingList{ResourceInterface: r.NamespaceableResourceInterface}.List(ctx, opts)
+}
+
+type truncatingList struct {
+	dynamic.ResourceInterface
+}
+
+func (l truncatingList) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	uList, err := l.ResourceInterface.List(ctx, opts)
+	if err == nil {
+		uList.SetContinue("next")
+	}
+	return uList, err
+}
+
+func customResource(kind string, namespace string, name string, uid string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("example.com/v1")
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	u.SetUID(types.UID(uid))
+	return u
+}
+
+func ownedBy(owner *unstructured.Unstructured) []metav1.OwnerReference {
+	return []metav1.OwnerReference{{APIVersion: owner.GetAPIVersion(), Kind: owner.GetKind(), Name: owner.GetName(), UID: owner.GetUID()}}
+}
+
+func TestFindChildren(t *testing.T) {
+	db := customResource("Database", "back-end", "orders", "db-uid")
+	owned := &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}, ObjectMeta: metav1.ObjectMeta{Name: "orders-config", Namespace: "back-end", OwnerReferences: ownedBy(db)}}
+	other := &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}, ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "back-end"}}
+	kc, client := fakeCluster(t, db, owned, other)
+
+	children, errors := kc.findChildren(context.Background(), db, schema.GroupKind{Group: "example.com", Kind: "Database"})
+	if len(errors) != 0 {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	if len(children) != 1 || children[0].Name != "orders-config" || children[0].GroupKind != (schema.GroupKind{Kind: "ConfigMap"}) {
+		t.Errorf("expected the owned config map, got %+v", children)
+	}
+
+	// A cluster scoped owner doesn't list its namespaced child kinds in every namespace
+	client.ClearActions()
+	tenant := customResource("Tenant", "", "acme", "tenant-uid")
+	children, errors = kc.findChildren(context.Background(), tenant, schema.GroupKind{Group: "example.com", Kind: "Tenant"})
+	if len(children) != 0 || len(errors) != 0 {
+		t.Errorf("expected no children, got %+v %v", children, errors)
+	}
+	if len(client.Actions()) != 0 {
+		t.Errorf("expected no lists, got %v", client.Actions())
+	}
+}
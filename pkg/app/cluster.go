@@ -0,0 +1,150 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/cheriot/kubenav/internal/copyofk8sprinters/internalversion"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	resourcehelper "k8s.io/kubectl/pkg/util/resource"
+)
+
+// ClusterOverview is the home page of a cluster: its nodes and how much of them is allocated.
+type ClusterOverview struct {
+	Nodes  []NodeSummary `json:"nodes"`
+	CPU    Allocation    `json:"cpu"`
+	Memory Allocation    `json:"memory"`
+	Pods   int           `json:"pods"`
+}
+
+type NodeSummary struct {
+	Name           string                 `json:"name"`
+	Roles          []string               `json:"roles"`
+	Health         Health                 `json:"health"`
+	Conditions     []corev1.NodeCondition `json:"conditions"`
+	Unschedulable  bool                   `json:"unschedulable"`
+	Taints         []corev1.Taint         `json:"taints"`
+	KubeletVersion string                 `json:"kubeletVersion"`
+	CPU            Allocation             `json:"cpu"`
+	Memory         Allocation             `json:"memory"`
+	// Non-terminated pods scheduled to the node and how many it allows
+	Pods        int   `json:"pods"`
+	PodCapacity int64 `json:"podCapacity"`
+}
+
+// Allocation of one resource, computed from the requests and limits in pod specs the same as kubectl describe node.
+// Limits may be over 100 percent.
+type Allocation struct {
+	Allocatable     resource.Quantity `json:"allocatable"`
+	Requests        resource.Quantity `json:"requests"`
+	Limits          resource.Quantity `json:"limits"`
+	RequestsPercent int64             `json:"requestsPercent"`
+	LimitsPercent   int64             `json:"limitsPercent"`
+}
+
+func (kc *KubeCluster) ClusterOverview(ctx context.Context) (*ClusterOverview, error) {
+	coreclient, err := corev1client.NewForConfig(kc.restClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create coreclient for %s: %w", kc.name, err)
+	}
+
+	nodes, err := coreclient.Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list nodes for %s: %w", kc.name, err)
+	}
+	// Terminated pods don't hold on to their requests
+	pods, err := coreclient.Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: "status.phase!=" + string(corev1.PodSucceeded) + ",status.phase!=" + string(corev1.PodFailed),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list pods for %s: %w", kc.name, err)
+	}
+
+	return summarizeCluster(nodes.Items, pods.Items), nil
+}
+
+func summarizeCluster(nodes []corev1.Node, pods []corev1.Pod) *ClusterOverview {
+	podsByNode := make(map[string][]corev1.Pod)
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+	}
+
+	overview := &ClusterOverview{Nodes: make([]NodeSummary, 0, len(nodes))}
+	var cpu, memory Allocation
+	for i := range nodes {
+		node := &nodes[i]
+		summary := summarizeNode(node, podsByNode[node.Name])
+		overview.Nodes = append(overview.Nodes, summary)
+		overview.Pods += summary.Pods
+
+		cpu.Allocatable.Add(summary.CPU.Allocatable)
+		cpu.Requests.Add(summary.CPU.Requests)
+		cpu.Limits.Add(summary.CPU.Limits)
+		memory.Allocatable.Add(summary.Memory.Allocatable)
+		memory.Requests.Add(summary.Memory.Requests)
+		memory.Limits.Add(summary.Memory.Limits)
+	}
+	overview.CPU = withPercents(cpu, true)
+	overview.Memory = withPercents(memory, false)
+
+	sort.Slice(overview.Nodes, func(i, j int) bool { return overview.Nodes[i].Name < overview.Nodes[j].Name })
+	return overview
+}
+
+func summarizeNode(node *corev1.Node, pods []corev1.Pod) NodeSummary {
+	allocatable := node.Status.Capacity
+	if len(node.Status.Allocatable) > 0 {
+		allocatable = node.Status.Allocatable
+	}
+
+	cpu := Allocation{Allocatable: allocatable.Cpu().DeepCopy()}
+	memory := Allocation{Allocatable: allocatable.Memory().DeepCopy()}
+	for i := range pods {
+		requests, limits := resourcehelper.PodRequestsAndLimits(&pods[i])
+		cpu.Requests.Add(requests[corev1.ResourceCPU])
+		cpu.Limits.Add(limits[corev1.ResourceCPU])
+		memory.Requests.Add(requests[corev1.ResourceMemory])
+		memory.Limits.Add(limits[corev1.ResourceMemory])
+	}
+
+	taints := node.Spec.Taints
+	if taints == nil {
+		taints = []corev1.Taint{}
+	}
+	return NodeSummary{
+		Name:           node.Name,
+		Roles:          internalversion.FindNodeRoles(node),
+		Health:         nodeHealth(node),
+		Conditions:     node.Status.Conditions,
+		Unschedulable:  node.Spec.Unschedulable,
+		Taints:         taints,
+		KubeletVersion: node.Status.NodeInfo.KubeletVersion,
+		CPU:            withPercents(cpu, true),
+		Memory:         withPercents(memory, false),
+		Pods:           len(pods),
+		PodCapacity:    allocatable.Pods().Value(),
+	}
+}
+
+// withPercents of allocatable. CPU is compared in millicores since most requests are fractions of a core.
+func withPercents(a Allocation, milli bool) Allocation {
+	value := func(q resource.Quantity) int64 {
+		if milli {
+			return q.MilliValue()
+		}
+		return q.Value()
+	}
+	if allocatable := value(a.Allocatable); allocatable != 0 {
+		a.RequestsPercent = value(a.Requests) * 100 / allocatable
+		a.LimitsPercent = value(a.Limits) * 100 / allocatable
+	}
+	return a
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSummarizeCluster(t *testing.T) {
+	node := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-1", Labels: map[string]string{"node-role.kubernetes.io/worker": ""}},
+		Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: "dedicated", Value: "batch", Effect: corev1.TaintEffectNoSchedule}}},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("2"),
+				corev1.ResourceMemory: resource.MustParse("4Gi"),
+				corev1.ResourcePods:   resource.MustParse("110"),
+			},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	container := func(cpu string, memory string) corev1.Container {
+		return corev1.Container{Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu), corev1.ResourceMemory: resource.MustParse(memory)},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(memory)},
+		}}
+	}
+	pods := []corev1.Pod{
+		{Spec: corev1.PodSpec{NodeName: "worker-1", Containers: []corev1.Container{container("500m", "1Gi"), container("250m", "512Mi")}}},
+		// Init containers run first so only the largest counts
+		{Spec: corev1.PodSpec{NodeName: "worker-1", InitContainers: []corev1.Container{container("1", "64Mi")}, Containers: []corev1.Container{container("250m", "512Mi")}}},
+		{Spec: corev1.PodSpec{NodeName: "worker-1", Containers: []corev1.Container{container("2", "2Gi")}}, Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+		{Spec: corev1.PodSpec{Containers: []corev1.Container{container("2", "2Gi")}}},
+	}
+
+	overview := summarizeCluster([]corev1.Node{node}, pods)
+	if overview.Pods != 2 || len(overview.Nodes) != 1 {
+		t.Fatalf("expected 2 pods on 1 node, got %+v", overview)
+	}
+	summary := overview.Nodes[0]
+	if !reflect.DeepEqual([]string{"worker"}, summary.Roles) {
+		t.Errorf("expected the worker role, got %v", summary.Roles)
+	}
+	if summary.Health.Status != Healthy || summary.PodCapacity != 110 || len(summary.Taints) != 1 {
+		t.Errorf("unexpected node summary %+v", summary)
+	}
+	if summary.CPU.Requests.MilliValue() != 1750 || summary.CPU.RequestsPercent != 87 {
+		t.Errorf("expected 1750m cpu requested, 87%%, got %s, %d%%", summary.CPU.Requests.String(), summary.CPU.RequestsPercent)
+	}
+	if summary.Memory.Requests.String() != "2Gi" || summary.Memory.LimitsPercent != 50 {
+		t.Errorf("expected 2Gi memory requested, 50%% limits, got %s, %d%%", summary.Memory.Requests.String(), summary.Memory.LimitsPercent)
+	}
+	if overview.CPU.RequestsPercent != summary.CPU.RequestsPercent {
+		t.Errorf("expected the cluster totals of its only node, got %+v", overview.CPU)
+	}
+}
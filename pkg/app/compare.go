@@ -0,0 +1,225 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/pmezard/go-difflib/difflib"
+	log "github.com/sirupsen/logrus"
+
+	util "github.com/cheriot/kubenav/internal/util"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Fields that the apiserver or controllers assign. They differ between any two clusters and hide the drift we care
+// about.
+var clusterAssignedFields = [][]string{
+	{"metadata", "uid"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "selfLink"},
+	{"metadata", "managedFields"},
+	{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"},
+	{"metadata", "annotations", "deployment.kubernetes.io/revision"},
+	{"status"},
+	{"spec", "clusterIP"},
+	{"spec", "clusterIPs"},
+}
+
+type NamespaceComparison struct {
+	LeftContext  string           `json:"leftContext"`
+	RightContext string           `json:"rightContext"`
+	Namespace    string           `json:"namespace"`
+	Kinds        []KindComparison `json:"kinds"`
+	// GroupKinds matching the query that only one of the clusters serves
+	OnlyLeftKinds  []schema.GroupKind `json:"onlyLeftKinds"`
+	OnlyRightKinds []schema.GroupKind `json:"onlyRightKinds"`
+}
+
+type KindComparison struct {
+	GroupKind schema.GroupKind `json:"groupKind"`
+	OnlyLeft  []string         `json:"onlyLeft"`
+	OnlyRight []string         `json:"onlyRight"`
+	Identical []string         `json:"identical"`
+	Different []ObjectDiff     `json:"different"`
+	ErrorMsg  string           `json:"error"`
+	// More than LIST_LIMIT objects in either cluster. Only the first of them were compared.
+	Truncated bool `json:"truncated"`
+}
+
+type ObjectDiff struct {
+	Name string `json:"name"`
+	// Unified diff of the normalized yaml
+	Diff string `json:"diff"`
+}
+
+// CompareNamespace lists every kind matching query in both contexts and diffs the objects by name.
+func CompareNamespace(ctx context.Context, leftCtxName string, rightCtxName string, nsName string, query string) (*NamespaceComparison, error) {
+	left, err := GetOrMakeKubeCluster(ctx, leftCtxName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get kubecluster for %s: %w", leftCtxName, err)
+	}
+	right, err := GetOrMakeKubeCluster(ctx, rightCtxName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get kubecluster for %s: %w", rightCtxName, err)
+	}
+
+	leftResources := listableByGK(findAPIResources(left.apiResources, query))
+	rightResources := listableByGK(findAPIResources(right.apiResources, query))
+	if len(leftResources) == 0 && len(rightResources) == 0 {
+		return nil, fmt.Errorf("no listable resources found matching %s", query)
+	}
+
+	comparison := &NamespaceComparison{
+		LeftContext:    leftCtxName,
+		RightContext:   rightCtxName,
+		Namespace:      nsName,
+		Kinds:          make([]KindComparison, 0),
+		OnlyLeftKinds:  make([]schema.GroupKind, 0),
+		OnlyRightKinds: make([]schema.GroupKind, 0),
+	}
+
+	for _, gk := range sortedGKs(leftResources) {
+		rightResource, found := rightResources[gk]
+		if !found {
+			comparison.OnlyLeftKinds = append(comparison.OnlyLeftKinds, gk)
+			continue
+		}
+		comparison.Kinds = append(comparison.Kinds, compareKind(ctx, left, leftResources[gk], right, rightResource, nsName))
+	}
+	for _, gk := range sortedGKs(rightResources) {
+		if _, found := leftResources[gk]; !found {
+			comparison.OnlyRightKinds = append(comparison.OnlyRightKinds, gk)
+		}
+	}
+
+	return comparison, nil
+}
+
+func compareKind(ctx context.Context, left *KubeCluster, leftResource metav1.APIResource, right *KubeCluster, rightResource metav1.APIResource, nsName string) KindComparison {
+	kc := KindComparison{
+		GroupKind: toGK(leftResource),
+		OnlyLeft:  make([]string, 0),
+		OnlyRight: make([]string, 0),
+		Identical: make([]string, 0),
+		Different: make([]ObjectDiff, 0),
+	}
+
+	leftList, err := left.listUnstructured(ctx, leftResource, nsName, metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("compare list error for %s in %s: %v", kc.GroupKind, left.name, err)
+		kc.ErrorMsg = err.Error()
+		return kc
+	}
+	rightList, err := right.listUnstructured(ctx, rightResource, nsName, metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("compare list error for %s in %s: %v", kc.GroupKind, right.name, err)
+		kc.ErrorMsg = err.Error()
+		return kc
+	}
+
+	if isTruncated(leftList) || isTruncated(rightList) {
+		log.Warnf("compare of %s in %s truncated at %d objects", kc.GroupKind, nsName, LIST_LIMIT)
+		kc.Truncated = true
+	}
+
+	rightByName := make(map[string]unstructured.Unstructured)
+	for _, item := range rightList.Items {
+		rightByName[item.GetName()] = item
+	}
+
+	for _, leftItem := range leftList.Items {
+		name := leftItem.GetName()
+		rightItem, found := rightByName[name]
+		if !found {
+			kc.OnlyLeft = append(kc.OnlyLeft, name)
+			continue
+		}
+		delete(rightByName, name)
+
+		diff, err := diffObjects(&leftItem, left.name, &rightItem, right.name)
+		if err != nil {
+			kc.Different = append(kc.Different, ObjectDiff{Name: name, Diff: err.Error()})
+		} else if diff == "" {
+			kc.Identical = append(kc.Identical, name)
+		} else {
+			kc.Different = append(kc.Different, ObjectDiff{Name: name, Diff: diff})
+		}
+	}
+	kc.OnlyRight = util.Keys(rightByName)
+	sort.Strings(kc.OnlyRight)
+
+	return kc
+}
+
+// diffObjects returns a unified diff of the normalized objects or "" if they're the same.
+func diffObjects(left *unstructured.Unstructured, leftLabel string, right *unstructured.Unstructured, rightLabel string) (string, error) {
+	leftYaml, err := renderYaml(normalizeForCompare(left))
+	if err != nil {
+		return "", fmt.Errorf("unable to render %s: %w", left.GetName(), err)
+	}
+	rightYaml, err := renderYaml(normalizeForCompare(right))
+	if err != nil {
+		return "", fmt.Errorf("unable to render %s: %w", right.GetName(), err)
+	}
+	if leftYaml == rightYaml {
+		return "", nil
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(leftYaml),
+		B:        difflib.SplitLines(rightYaml),
+		FromFile: leftLabel,
+		ToFile:   rightLabel,
+		Context:  3,
+	})
+}
+
+// normalizeForCompare returns a copy of u without the fields a cluster assigns on its own.
+func normalizeForCompare(u *unstructured.Unstructured) *unstructured.Unstructured {
+	normalized := u.DeepCopy()
+	for _, fields := range clusterAssignedFields {
+		unstructured.RemoveNestedField(normalized.Object, fields...)
+	}
+
+	if len(normalized.GetAnnotations()) == 0 {
+		unstructured.RemoveNestedField(normalized.Object, "metadata", "annotations")
+	}
+
+	// Owners are recreated per cluster, so only their uid differs.
+	owners := normalized.GetOwnerReferences()
+	for i := range owners {
+		owners[i].UID = ""
+	}
+	if len(owners) > 0 {
+		normalized.SetOwnerReferences(owners)
+	}
+
+	return normalized
+}
+
+func listableByGK(apiResources []metav1.APIResource) map[schema.GroupKind]metav1.APIResource {
+	byGK := make(map[schema.GroupKind]metav1.APIResource)
+	for _, r := range apiResources {
+		if util.Contains(r.Verbs, "list") {
+			byGK[toGK(r)] = r
+		}
+	}
+	return byGK
+}
+
+func sortedGKs(byGK map[schema.GroupKind]metav1.APIResource) []schema.GroupKind {
+	gks := util.Keys(byGK)
+	sort.Slice(gks, func(i, j int) bool {
+		if gks[i].Group != gks[j].Group {
+			return gks[i].Group < gks[j].Group
+		}
+		return gks[i].Kind < gks[j].Kind
+	})
+	return gks
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func configMap(uid string, resourceVersion string, value string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":              "settings",
+			"namespace":         "default",
+			"uid":               uid,
+			"resourceVersion":   resourceVersion,
+			"creationTimestamp": "2022-08-01T00:00:00Z",
+		},
+		"data": map[string]interface{}{"level": value},
+	}}
+}
+
+func TestDiffObjectsIgnoresClusterAssignedFields(t *testing.T) {
+	left := configMap("aaa", "1", "info")
+	right := configMap("bbb", "2", "info")
+
+	diff, err := diffObjects(left, "staging", right, "prod")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff != "" {
+		t.Errorf("expected no diff, got:\n%s", diff)
+	}
+}
+
+func TestDiffObjectsReportsDrift(t *testing.T) {
+	left := configMap("aaa", "1", "info")
+	right := configMap("bbb", "2", "debug")
+
+	diff, err := diffObjects(left, "staging", right, "prod")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"--- staging", "+++ prod", "-    level: info", "+    level: debug"} {
+		if !strings.Contains(diff, expected) {
+			t.Errorf("expected diff to contain %q, got:\n%s", expected, diff)
+		}
+	}
+	if strings.Contains(diff, "uid") {
+		t.Errorf("expected uid to be normalized away, got:\n%s", diff)
+	}
+}
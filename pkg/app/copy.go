@@ -0,0 +1,121 @@
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// Copies run tar in the container, like kubectl cp, so the image must include a tar binary.
+
+type CopyOptions struct {
+	Namespace string
+	Pod       string
+	Container string
+	// Absolute path in the container. A file or directory to download, the directory to upload into.
+	Path string
+}
+
+// CopyFromContainer writes a tar stream of the file or directory at opts.Path to w. Nothing is written to w when the
+// exec fails before tar starts.
+func (kc *KubeCluster) CopyFromContainer(opts CopyOptions, w io.Writer) error {
+	src := path.Clean(opts.Path)
+	if !path.IsAbs(src) || src == "/" {
+		return fmt.Errorf("expected an absolute path to a file or directory, got '%s'", opts.Path)
+	}
+
+	stderr := &bytes.Buffer{}
+	executor, err := kc.podExecutor(ExecOptions{
+		Namespace: opts.Namespace,
+		Pod:       opts.Pod,
+		Container: opts.Container,
+		Command:   []string{"tar", "cf", "-", "-C", path.Dir(src), path.Base(src)},
+	}, false)
+	if err != nil {
+		return err
+	}
+
+	err = executor.Stream(remotecommand.StreamOptions{Stdout: w, Stderr: stderr})
+	if err != nil {
+		return fmt.Errorf("unable to download %s from %s/%s: %w: %s", src, opts.Namespace, opts.Pod, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// CopyToContainer writes the size bytes of r to a file named fileName in the directory opts.Path. Canceling ctx ends
+// tar's stdin early, which fails the upload.
+func (kc *KubeCluster) CopyToContainer(ctx context.Context, opts CopyOptions, fileName string, size int64, r io.Reader) error {
+	destDir := path.Clean(opts.Path)
+	if !path.IsAbs(destDir) {
+		return fmt.Errorf("expected an absolute directory path, got '%s'", opts.Path)
+	}
+	// . and .. would be the directory itself or its parent
+	if fileName == "" || fileName == "." || fileName == ".." || strings.Contains(fileName, "/") {
+		return fmt.Errorf("expected a file name without a directory, got '%s'", fileName)
+	}
+
+	stderr := &bytes.Buffer{}
+	executor, err := kc.podExecutor(ExecOptions{
+		Namespace: opts.Namespace,
+		Pod:       opts.Pod,
+		Container: opts.Container,
+		Command:   []string{"tar", "xmf", "-", "-C", destDir},
+	}, true)
+	if err != nil {
+		return err
+	}
+
+	tarReader, tarWriter := io.Pipe()
+	go func() {
+		tarWriter.CloseWithError(writeSingleFileTar(tarWriter, fileName, size, r))
+	}()
+
+	// The executor can't be canceled, but closing stdin ends the remote tar.
+	streamed := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			tarReader.Close()
+		case <-streamed:
+		}
+	}()
+
+	err = executor.Stream(remotecommand.StreamOptions{Stdin: tarReader, Stdout: io.Discard, Stderr: stderr})
+	close(streamed)
+	// Unblock the tar writer if the exec ended before reading all of stdin
+	tarReader.Close()
+	if ctx.Err() != nil {
+		// Rather than however tar saw its stdin end
+		err = ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("unable to upload %s to %s/%s:%s: %w: %s", fileName, opts.Namespace, opts.Pod, destDir, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func writeSingleFileTar(w io.Writer, fileName string, size int64, r io.Reader) error {
+	tw := tar.NewWriter(w)
+	err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     fileName,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to write tar header for %s: %w", fileName, err)
+	}
+
+	if _, err := io.CopyN(tw, r, size); err != nil {
+		return fmt.Errorf("unable to write %s to tar: %w", fileName, err)
+	}
+	return tw.Close()
+}
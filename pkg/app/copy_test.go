@@ -0,0 +1,185 @@
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// scriptedExecutor runs stream in place of the exec and remembers the url it was made for.
+type scriptedExecutor struct {
+	url    *url.URL
+	stream func(options remotecommand.StreamOptions) error
+}
+
+func (e *scriptedExecutor) Stream(options remotecommand.StreamOptions) error {
+	return e.stream(options)
+}
+
+func copyTestCluster(executor *scriptedExecutor) *KubeCluster {
+	return &KubeCluster{
+		name:             "test",
+		restClientConfig: &restclient.Config{Host: "https://127.0.0.1:6443"},
+		newExecutor: func(config *restclient.Config, method string, u *url.URL) (remotecommand.Executor, error) {
+			executor.url = u
+			return executor, nil
+		},
+	}
+}
+
+func TestCopyFromContainer(t *testing.T) {
+	executor := &scriptedExecutor{stream: func(options remotecommand.StreamOptions) error {
+		_, err := options.Stdout.Write([]byte("tar bytes"))
+		return err
+	}}
+	kc := copyTestCluster(executor)
+
+	var out bytes.Buffer
+	opts := CopyOptions{Namespace: "back-end", Pod: "product-a", Container: "app", Path: "/var/log/app/"}
+	if err := kc.CopyFromContainer(opts, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	query := executor.url.Query()
+	expected := []string{"tar", "cf", "-", "-C", "/var/log", "app"}
+	if !reflect.DeepEqual(query["command"], expected) {
+		t.Errorf("expected command %v, got %v", expected, query["command"])
+	}
+	if query.Get("container") != "app" || query.Get("stdin") == "true" {
+		t.Errorf("unexpected exec query %s", executor.url.RawQuery)
+	}
+	if out.String() != "tar bytes" {
+		t.Errorf("unexpected stdout %q", out.String())
+	}
+}
+
+func TestCopyFromContainerErrors(t *testing.T) {
+	executor := &scriptedExecutor{stream: func(options remotecommand.StreamOptions) error {
+		options.Stderr.Write([]byte("tar: missing: No such file or directory\n"))
+		return errors.New("command terminated with exit code 2")
+	}}
+	kc := copyTestCluster(executor)
+
+	var out bytes.Buffer
+	err := kc.CopyFromContainer(CopyOptions{Namespace: "back-end", Pod: "product-a", Path: "/missing"}, &out)
+	if err == nil || !strings.Contains(err.Error(), "exit code 2") || !strings.Contains(err.Error(), "No such file or directory") {
+		t.Errorf("expected the exec error and stderr, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected nothing written, got %q", out.String())
+	}
+
+	for _, p := range []string{"", "relative/path", "/"} {
+		if err := kc.CopyFromContainer(CopyOptions{Path: p}, &out); err == nil {
+			t.Errorf("expected an error for path '%s'", p)
+		}
+	}
+}
+
+func TestCopyToContainer(t *testing.T) {
+	var header *tar.Header
+	var content []byte
+	executor := &scriptedExecutor{stream: func(options remotecommand.StreamOptions) error {
+		tr := tar.NewReader(options.Stdin)
+		var err error
+		if header, err = tr.Next(); err != nil {
+			return err
+		}
+		if content, err = io.ReadAll(tr); err != nil {
+			return err
+		}
+		_, err = tr.Next()
+		if err != io.EOF {
+			return errors.New("expected a single file")
+		}
+		return nil
+	}}
+	kc := copyTestCluster(executor)
+
+	opts := CopyOptions{Namespace: "back-end", Pod: "product-a", Container: "app", Path: "/tmp"}
+	err := kc.CopyToContainer(context.Background(), opts, "config.yaml", 9, strings.NewReader("key: val\nignored"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	query := executor.url.Query()
+	expected := []string{"tar", "xmf", "-", "-C", "/tmp"}
+	if !reflect.DeepEqual(query["command"], expected) {
+		t.Errorf("expected command %v, got %v", expected, query["command"])
+	}
+	if query.Get("stdin") != "true" {
+		t.Errorf("unexpected exec query %s", executor.url.RawQuery)
+	}
+	if header.Name != "config.yaml" || header.Size != 9 || header.Typeflag != tar.TypeReg {
+		t.Errorf("unexpected header %+v", header)
+	}
+	if string(content) != "key: val\n" {
+		t.Errorf("unexpected content %q", content)
+	}
+}
+
+func TestCopyToContainerErrors(t *testing.T) {
+	// Fails without reading stdin, which must not block the tar writer
+	executor := &scriptedExecutor{stream: func(options remotecommand.StreamOptions) error {
+		options.Stderr.Write([]byte("tar: /readonly: Cannot open: Read-only file system"))
+		return errors.New("command terminated with exit code 2")
+	}}
+	kc := copyTestCluster(executor)
+
+	opts := CopyOptions{Namespace: "back-end", Pod: "product-a", Path: "/readonly"}
+	err := kc.CopyToContainer(context.Background(), opts, "big.bin", 1<<20, bytes.NewReader(make([]byte, 1<<20)))
+	if err == nil || !strings.Contains(err.Error(), "exit code 2") || !strings.Contains(err.Error(), "Read-only file system") {
+		t.Errorf("expected the exec error and stderr, got %v", err)
+	}
+
+	for _, fileName := range []string{"", ".", "..", "dir/file"} {
+		if err := kc.CopyToContainer(context.Background(), opts, fileName, 0, strings.NewReader("")); err == nil {
+			t.Errorf("expected an error for file name '%s'", fileName)
+		}
+	}
+	if err := kc.CopyToContainer(context.Background(), CopyOptions{Path: "tmp"}, "file", 0, strings.NewReader("")); err == nil {
+		t.Error("expected an error for a relative directory")
+	}
+}
+
+func TestCopyToContainerCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	executor := &scriptedExecutor{stream: func(options remotecommand.StreamOptions) error {
+		cancel()
+		_, err := io.Copy(io.Discard, options.Stdin)
+		return err
+	}}
+	kc := copyTestCluster(executor)
+
+	// The file never ends, so only the cancel stops the upload
+	err := kc.CopyToContainer(ctx, CopyOptions{Path: "/tmp"}, "endless", 1<<40, zeroReader{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestWriteSingleFileTarShortReader(t *testing.T) {
+	var out bytes.Buffer
+	err := writeSingleFileTar(&out, "short.txt", 10, strings.NewReader("abc"))
+	if err == nil || !strings.Contains(err.Error(), "short.txt") {
+		t.Errorf("expected an error for a reader shorter than size, got %v", err)
+	}
+}
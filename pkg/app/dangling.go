@@ -0,0 +1,259 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/cheriot/kubenav/pkg/app/relations"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var (
+	podGK     = schema.GroupKind{Kind: "Pod"}
+	serviceGK = schema.GroupKind{Kind: "Service"}
+	ingressGK = schema.GroupKind{Group: networkingv1.GroupName, Kind: "Ingress"}
+)
+
+// DanglingReport lists the references in a namespace to objects that don't exist.
+type DanglingReport struct {
+	Namespace  string              `json:"namespace"`
+	References []DanglingReference `json:"references"`
+	// ie a kind that couldn't be listed, so references to it weren't checked
+	Errors []string `json:"errors"`
+}
+
+type DanglingReference struct {
+	Origin relations.HasOneDestination `json:"origin"`
+	// The Name is empty when a selector matches nothing
+	Missing relations.HasOneDestination `json:"missing"`
+	Reason  string                      `json:"reason"`
+}
+
+// DanglingReferences finds the most common misconfigurations in a namespace: references to missing objects, ie a
+// pod's ConfigMap, Services that select no pods, Ingress backends to missing Services or ports and ownerReferences to
+// deleted owners. Only references within the namespace are checked. Optional references, ie a pod's Secret volume
+// with optional: true, aren't dangling.
+func (kc *KubeCluster) DanglingReferences(ctx context.Context, nsName string) *DanglingReport {
+	report := &DanglingReport{
+		Namespace:  nsName,
+		References: make([]DanglingReference, 0),
+		Errors:     make([]string, 0),
+	}
+
+	objects := make(map[schema.GroupKind][]relations.Relatable)
+	listed := make(map[schema.GroupKind]bool)
+	list := func(gk schema.GroupKind) {
+		if _, done := objects[gk]; done {
+			return
+		}
+		objects[gk] = []relations.Relatable{}
+		apiResource, found := findAPIResourceByGK(kc.apiResources, gk)
+		if !found || !apiResource.Namespaced {
+			return
+		}
+		items, errors := kc.listRelatable(ctx, gk, nsName)
+		for _, err := range errors {
+			report.Errors = append(report.Errors, err.Error())
+		}
+		objects[gk] = items
+		// Including a truncatedListError. The objects past LIST_LIMIT would look missing.
+		listed[gk] = len(errors) == 0
+	}
+
+	for _, gk := range relations.GraphKinds() {
+		list(gk)
+	}
+	// Then the kinds they reference, ie ServiceAccounts, and the kinds of their owners
+	for gk, items := range copyObjects(objects) {
+		for _, obj := range items {
+			for _, d := range relations.RelationsList(obj, gk) {
+				list(d.GroupKind)
+			}
+			for _, owner := range relations.OwnerList(obj) {
+				list(owner.GroupKind)
+			}
+		}
+	}
+
+	report.References = danglingReferences(nsName, objects, listed)
+	return report
+}
+
+func copyObjects(objects map[schema.GroupKind][]relations.Relatable) map[schema.GroupKind][]relations.Relatable {
+	c := make(map[schema.GroupKind][]relations.Relatable, len(objects))
+	for gk, items := range objects {
+		c[gk] = items
+	}
+	return c
+}
+
+// danglingReferences checks the objects of a namespace against each other. listed are the kinds that were listed
+// successfully. References to other kinds can't be checked.
+func danglingReferences(nsName string, objects map[schema.GroupKind][]relations.Relatable, listed map[schema.GroupKind]bool) []DanglingReference {
+	names := make(map[string]bool)
+	uids := make(map[types.UID]bool)
+	for gk, items := range objects {
+		for _, obj := range items {
+			if d, ok := graphDestination(gk, obj); ok {
+				names[relations.NodeID(d)] = true
+			}
+			if accessor, err := meta.Accessor(obj); err == nil {
+				uids[accessor.GetUID()] = true
+			}
+		}
+	}
+
+	dangling := make([]DanglingReference, 0)
+	for gk, items := range objects {
+		for _, obj := range items {
+			origin, ok := graphDestination(gk, obj)
+			if !ok {
+				continue
+			}
+
+			for _, d := range relations.RelationsList(obj, gk) {
+				if d.Namespace == nsName && !d.Optional && listed[d.GroupKind] && !names[relations.NodeID(d)] {
+					dangling = append(dangling, DanglingReference{
+						Origin:  origin,
+						Missing: d,
+						Reason:  missingReason(d),
+					})
+				}
+			}
+
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				continue
+			}
+			for _, or := range accessor.GetOwnerReferences() {
+				gv, err := schema.ParseGroupVersion(or.APIVersion)
+				if err != nil {
+					continue
+				}
+				owner := relations.HasOneDestination{GroupKind: gv.WithKind(or.Kind).GroupKind(), Namespace: nsName, Name: or.Name}
+				if !listed[owner.GroupKind] || uids[or.UID] {
+					continue
+				}
+				reason := fmt.Sprintf("owner %s %s was deleted", or.Kind, or.Name)
+				if names[relations.NodeID(owner)] {
+					reason = fmt.Sprintf("owner %s %s was deleted and recreated", or.Kind, or.Name)
+				}
+				dangling = append(dangling, DanglingReference{Origin: origin, Missing: owner, Reason: reason})
+			}
+		}
+	}
+
+	if listed[podGK] {
+		dangling = append(dangling, emptyServiceSelectors(objects[serviceGK], objects[podGK])...)
+	}
+	if listed[serviceGK] {
+		dangling = append(dangling, missingIngressPorts(objects[ingressGK], objects[serviceGK])...)
+	}
+
+	sort.SliceStable(dangling, func(i, j int) bool {
+		return relations.NodeID(dangling[i].Origin) < relations.NodeID(dangling[j].Origin)
+	})
+	return dangling
+}
+
+func missingReason(d relations.HasOneDestination) string {
+	if d.Label == "" {
+		return fmt.Sprintf("%s %s doesn't exist", d.Kind, d.Name)
+	}
+	return fmt.Sprintf("%s %s, used by %s, doesn't exist", d.Kind, d.Name, d.Label)
+}
+
+func emptyServiceSelectors(services []relations.Relatable, pods []relations.Relatable) []DanglingReference {
+	dangling := make([]DanglingReference, 0)
+	for _, s := range services {
+		typed, err := relations.Typed(s)
+		if err != nil {
+			continue
+		}
+		svc, ok := typed.(*corev1.Service)
+		if !ok || len(svc.Spec.Selector) == 0 {
+			continue
+		}
+
+		selector := labels.SelectorFromSet(svc.Spec.Selector)
+		matched := false
+		for _, p := range pods {
+			accessor, err := meta.Accessor(p)
+			if err == nil && selector.Matches(labels.Set(accessor.GetLabels())) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			dangling = append(dangling, DanglingReference{
+				Origin:  relations.HasOneDestination{GroupKind: serviceGK, Namespace: svc.Namespace, Name: svc.Name},
+				Missing: relations.HasOneDestination{GroupKind: podGK, Namespace: svc.Namespace},
+				Reason:  fmt.Sprintf("selector %s matches no pods", selector.String()),
+			})
+		}
+	}
+	return dangling
+}
+
+// missingIngressPorts finds backends to a port the Service doesn't have. Missing Services are already found by the
+// Ingress's relations.
+func missingIngressPorts(ingresses []relations.Relatable, services []relations.Relatable) []DanglingReference {
+	servicesByName := make(map[string]*corev1.Service)
+	for _, s := range services {
+		if typed, err := relations.Typed(s); err == nil {
+			if svc, ok := typed.(*corev1.Service); ok {
+				servicesByName[svc.Name] = svc
+			}
+		}
+	}
+
+	dangling := make([]DanglingReference, 0)
+	for _, i := range ingresses {
+		typed, err := relations.Typed(i)
+		if err != nil {
+			continue
+		}
+		ingress, ok := typed.(*networkingv1.Ingress)
+		if !ok {
+			continue
+		}
+
+		for _, backend := range relations.IngressServiceBackends(ingress) {
+			svc, found := servicesByName[backend.Service.Name]
+			if !found || hasServicePort(svc, backend.Service.Port) {
+				continue
+			}
+			dangling = append(dangling, DanglingReference{
+				Origin:  relations.HasOneDestination{GroupKind: ingressGK, Namespace: ingress.Namespace, Name: ingress.Name},
+				Missing: relations.HasOneDestination{GroupKind: serviceGK, Namespace: svc.Namespace, Name: svc.Name, Label: backend.Label()},
+				Reason:  fmt.Sprintf("service %s has no port %s", svc.Name, servicePortString(backend.Service.Port)),
+			})
+		}
+	}
+	return dangling
+}
+
+// servicePortString is the port's name, or its number if it has none.
+func servicePortString(port networkingv1.ServiceBackendPort) string {
+	if port.Name != "" {
+		return port.Name
+	}
+	return strconv.Itoa(int(port.Number))
+}
+
+func hasServicePort(svc *corev1.Service, port networkingv1.ServiceBackendPort) bool {
+	for _, sp := range svc.Spec.Ports {
+		if (port.Name != "" && sp.Name == port.Name) || (port.Name == "" && sp.Port == port.Number) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cheriot/kubenav/pkg/app/relations"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestDanglingReferences(t *testing.T) {
+	configMapGK := schema.GroupKind{Kind: "ConfigMap"}
+	secretGK := schema.GroupKind{Kind: "Secret"}
+	replicaSetGK := schema.GroupKind{Group: "apps", Kind: "ReplicaSet"}
+
+	isController := true
+	optional := true
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "product-5d8f-x", Namespace: "back-end", UID: "p-1", Labels: map[string]string{"app": "product"},
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "product-5d8f", UID: "rs-0", Controller: &isController}},
+		},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{
+					Name:         "config",
+					VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "product"}}},
+				},
+				// The pod starts without them, so they aren't dangling
+				{
+					Name:         "overrides",
+					VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "product-overrides"}, Optional: &optional}},
+				},
+				{
+					Name: "certs",
+					VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: []corev1.VolumeProjection{
+						{Secret: &corev1.SecretProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "product-tls"}, Optional: &optional}},
+					}}},
+				},
+			},
+			Containers: []corev1.Container{{
+				Name: "app",
+				EnvFrom: []corev1.EnvFromSource{
+					{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "product-env"}, Optional: &optional}},
+				},
+				Env: []corev1.EnvVar{{
+					Name: "LOG_LEVEL",
+					ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "logging"}, Key: "level", Optional: &optional,
+					}},
+				}},
+			}},
+		},
+	}
+	// Recreated with a new uid
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "product-5d8f", Namespace: "back-end", UID: "rs-1"}}
+	selectsNothing := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "cart", Namespace: "back-end"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "cart"}},
+	}
+	product := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "product", Namespace: "back-end"},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "product"},
+			Ports:    []corev1.ServicePort{{Name: "http", Port: 80}},
+		},
+	}
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "back-end"},
+		Spec: networkingv1.IngressSpec{DefaultBackend: &networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+			Name: "product", Port: networkingv1.ServiceBackendPort{Name: "grpc"},
+		}}},
+	}
+
+	objects := map[schema.GroupKind][]relations.Relatable{
+		podGK:        {pod},
+		replicaSetGK: {rs},
+		configMapGK:  {},
+		secretGK:     {},
+		serviceGK:    {selectsNothing, product},
+		ingressGK:    {ingress},
+	}
+	listed := map[schema.GroupKind]bool{podGK: true, replicaSetGK: true, configMapGK: true, secretGK: true, serviceGK: true, ingressGK: true}
+
+	actual := danglingReferences("back-end", objects, listed)
+
+	reasons := make(map[string]string)
+	for _, d := range actual {
+		reasons[relations.NodeID(d.Origin)+" "+d.Missing.Kind] = d.Reason
+	}
+	expected := map[string]string{
+		"Ingress.networking.k8s.io/back-end/shop Service": "service product has no port grpc",
+		"Pod/back-end/product-5d8f-x ConfigMap":           "ConfigMap product, used by volume config, doesn't exist",
+		"Pod/back-end/product-5d8f-x ReplicaSet":          "owner ReplicaSet product-5d8f was deleted and recreated",
+		"Service/back-end/cart Pod":                       "selector app=cart matches no pods",
+	}
+	if len(actual) != len(expected) {
+		t.Errorf("expected %d dangling references, got %+v", len(expected), actual)
+	}
+	for key, reason := range expected {
+		if reasons[key] != reason {
+			t.Errorf("%s: expected %q, got %q", key, reason, reasons[key])
+		}
+	}
+}
+
+func TestDanglingReferencesTruncated(t *testing.T) {
+	pod := readyPod("product-a", nil)
+	pod.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}
+	pod.Spec.Volumes = []corev1.Volume{{
+		Name:         "config",
+		VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "product"}}},
+	}}
+	kc, client := fakeCluster(t, pod)
+	report := kc.DanglingReferences(context.Background(), "back-end")
+	if len(report.References) != 1 || report.References[0].Missing.Name != "product" {
+		t.Fatalf("expected the missing config map, got %+v", report.References)
+	}
+
+	// The config map may be past the first LIST_LIMIT
+	kc.dynamicClient = truncatingClient{Interface: client, resource: "configmaps"}
+	report = kc.DanglingReferences(context.Background(), "back-end")
+	if len(report.References) != 0 {
+		t.Errorf("expected no dangling references, got %+v", report.References)
+	}
+	if len(report.Errors) != 1 || !strings.Contains(report.Errors[0], "only the first 1000 ConfigMap in back-end were listed") {
+		t.Errorf("expected a truncated config maps error, got %v", report.Errors)
+	}
+}
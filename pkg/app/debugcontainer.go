@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	DefaultDebugImage = "busybox:1.35"
+	debugStartTimeout = 2 * time.Minute
+)
+
+type DebugOptions struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	// DefaultDebugImage if empty
+	Image string `json:"image"`
+	// Share the process namespace of this container. Empty for none.
+	TargetContainer string `json:"targetContainer"`
+}
+
+type DebugContainer struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	// Exec into this container to use it
+	Container string `json:"container"`
+}
+
+// DebugPod adds an ephemeral container to the pod and waits for it to run. Exec into the returned container name to
+// use it.
+func (kc *KubeCluster) DebugPod(ctx context.Context, opts DebugOptions) (string, error) {
+	coreclient, err := corev1client.NewForConfig(kc.restClientConfig)
+	if err != nil {
+		return "", fmt.Errorf("unable to create coreclient for %s: %w", kc.name, err)
+	}
+	return debugPod(ctx, coreclient, opts)
+}
+
+func debugPod(ctx context.Context, coreclient corev1client.CoreV1Interface, opts DebugOptions) (string, error) {
+	pod, err := coreclient.Pods(opts.Namespace).Get(ctx, opts.Pod, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("unable to get pod %s/%s: %w", opts.Namespace, opts.Pod, err)
+	}
+
+	image := opts.Image
+	if image == "" {
+		image = DefaultDebugImage
+	}
+
+	name := fmt.Sprintf("debugger-%s", utilrand.String(5))
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:                     name,
+			Image:                    image,
+			ImagePullPolicy:          corev1.PullIfNotPresent,
+			TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+			// Keep the image's shell running so there's something to exec into
+			Stdin: true,
+			TTY:   true,
+		},
+		TargetContainerName: opts.TargetContainer,
+	})
+
+	_, err = coreclient.Pods(opts.Namespace).UpdateEphemeralContainers(ctx, opts.Pod, pod, metav1.UpdateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("unable to add ephemeral container to %s/%s: %w", opts.Namespace, opts.Pod, err)
+	}
+
+	err = wait.PollImmediateWithContext(ctx, time.Second, debugStartTimeout, func(ctx context.Context) (bool, error) {
+		pod, err := coreclient.Pods(opts.Namespace).Get(ctx, opts.Pod, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return isEphemeralContainerRunning(pod, name)
+	})
+	if err != nil {
+		return "", fmt.Errorf("ephemeral container %s in %s/%s did not start: %w", name, opts.Namespace, opts.Pod, err)
+	}
+
+	return name, nil
+}
+
+// isEphemeralContainerRunning errors once the container can't be expected to start.
+func isEphemeralContainerRunning(pod *corev1.Pod, name string) (bool, error) {
+	for _, status := range pod.Status.EphemeralContainerStatuses {
+		if status.Name != name {
+			continue
+		}
+
+		switch {
+		case status.State.Running != nil:
+			return true, nil
+		case status.State.Terminated != nil:
+			return false, fmt.Errorf("terminated: %s %s", status.State.Terminated.Reason, status.State.Terminated.Message)
+		case status.State.Waiting != nil && isImageError(status.State.Waiting.Reason):
+			return false, fmt.Errorf("%s: %s", status.State.Waiting.Reason, status.State.Waiting.Message)
+		}
+	}
+	return false, nil
+}
+
+func isImageError(reason string) bool {
+	switch reason {
+	case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "ErrImageNeverPull":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// startEphemeralContainers plays the kubelet: once a pod has been read after pending gets, its ephemeral containers
+// are in state.
+func startEphemeralContainers(client *fake.Clientset, pending int, state corev1.ContainerState) {
+	gets := 0
+	client.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		get := action.(k8stesting.GetAction)
+		obj, err := client.Tracker().Get(action.GetResource(), get.GetNamespace(), get.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		pod := obj.(*corev1.Pod)
+		gets++
+		for _, ec := range pod.Spec.EphemeralContainers {
+			status := corev1.ContainerStatus{Name: ec.Name, State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}}
+			if gets > pending {
+				status.State = state
+			}
+			pod.Status.EphemeralContainerStatuses = append(pod.Status.EphemeralContainerStatuses, status)
+		}
+		return true, pod, nil
+	})
+}
+
+func TestDebugPod(t *testing.T) {
+	pod := readyPod("product-a", nil)
+	client := fake.NewSimpleClientset(pod)
+	// The first get is before the update and the second is the first poll
+	startEphemeralContainers(client, 2, corev1.ContainerState{Running: &corev1.ContainerStateRunning{}})
+
+	opts := DebugOptions{Namespace: "back-end", Pod: "product-a", TargetContainer: "app"}
+	name, err := debugPod(context.Background(), client.CoreV1(), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(name, "debugger-") {
+		t.Errorf("unexpected container name %s", name)
+	}
+
+	var updated *corev1.Pod
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "update" && action.GetSubresource() == "ephemeralcontainers" {
+			updated = action.(k8stesting.UpdateAction).GetObject().(*corev1.Pod)
+		}
+	}
+	if updated == nil || len(updated.Spec.EphemeralContainers) != 1 {
+		t.Fatalf("expected an update with one ephemeral container, got %+v", updated)
+	}
+	ec := updated.Spec.EphemeralContainers[0]
+	if ec.Name != name || ec.Image != DefaultDebugImage || ec.TargetContainerName != "app" || !ec.Stdin || !ec.TTY {
+		t.Errorf("unexpected ephemeral container %+v", ec)
+	}
+}
+
+func TestDebugPodFailsToStart(t *testing.T) {
+	client := fake.NewSimpleClientset(readyPod("product-a", nil))
+	startEphemeralContainers(client, 0, corev1.ContainerState{
+		Waiting: &corev1.ContainerStateWaiting{Reason: "ErrImagePull", Message: "not found"},
+	})
+
+	opts := DebugOptions{Namespace: "back-end", Pod: "product-a", Image: "busybox:typo"}
+	_, err := debugPod(context.Background(), client.CoreV1(), opts)
+	if err == nil || !strings.Contains(err.Error(), "ErrImagePull") {
+		t.Errorf("expected an image error, got %v", err)
+	}
+
+	_, err = debugPod(context.Background(), client.CoreV1(), DebugOptions{Namespace: "back-end", Pod: "missing"})
+	if err == nil || !strings.Contains(err.Error(), "unable to get pod") {
+		t.Errorf("expected a missing pod error, got %v", err)
+	}
+}
+
+func TestIsEphemeralContainerRunning(t *testing.T) {
+	status := func(name string, state corev1.ContainerState) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "product-a"},
+			Status: corev1.PodStatus{EphemeralContainerStatuses: []corev1.ContainerStatus{
+				{Name: "debugger-old", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Completed"}}},
+				{Name: name, State: state},
+			}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		pod     *corev1.Pod
+		running bool
+		err     string
+	}{
+		{"not reported yet", &corev1.Pod{}, false, ""},
+		{"creating", status("debugger-new", corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}), false, ""},
+		{"running", status("debugger-new", corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}), true, ""},
+		{"terminated", status("debugger-new", corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error"}}), false, "terminated: Error"},
+		{"image error", status("debugger-new", corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}), false, "ImagePullBackOff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			running, err := isEphemeralContainerRunning(tt.pod, "debugger-new")
+			if running != tt.running {
+				t.Errorf("expected running %v, got %v", tt.running, running)
+			}
+			if (tt.err == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+var defaultExecCommand = []string{"/bin/sh"}
+
+// ExecutorFactory matches remotecommand.NewSPDYExecutor so tests can stand in their own executor.
+type ExecutorFactory func(config *restclient.Config, method string, url *url.URL) (remotecommand.Executor, error)
+
+type ExecOptions struct {
+	Namespace string
+	Pod       string
+	// Empty lets the apiserver choose the default container.
+	Container string
+	Command   []string
+	TTY       bool
+}
+
+// Ops of the json messages exchanged with the browser terminal.
+const (
+	TerminalOpStdin  = "stdin"  // client -> server, Data
+	TerminalOpResize = "resize" // client -> server, Cols and Rows
+	TerminalOpStdout = "stdout" // server -> client, Data
+	TerminalOpStderr = "stderr" // server -> client, Data
+	TerminalOpExit   = "exit"   // server -> client, Data is the error if the command failed
+)
+
+type TerminalMessage struct {
+	Op   string `json:"op"`
+	Data string `json:"data,omitempty"`
+	Cols uint16 `json:"cols,omitempty"`
+	Rows uint16 `json:"rows,omitempty"`
+}
+
+// TerminalConn carries TerminalMessages to and from the client. localserver implements it with a websocket.
+type TerminalConn interface {
+	Receive() (TerminalMessage, error)
+	Send(TerminalMessage) error
+	Close() error
+}
+
+// Exec runs a command in a container and streams its stdin, stdout, stderr and terminal size over conn until the
+// command exits, conn is closed, or ctx is done.
+func (kc *KubeCluster) Exec(ctx context.Context, opts ExecOptions, conn TerminalConn) error {
+	if len(opts.Command) == 0 {
+		opts.Command = defaultExecCommand
+	}
+
+	executor, err := kc.podExecutor(opts, true)
+	if err != nil {
+		return err
+	}
+
+	session := newTerminalSession(conn)
+	defer session.close()
+
+	// The executor doesn't take a context. Closing conn ends the command's stdin, which ends the command.
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-session.done:
+		}
+	}()
+
+	streamOptions := remotecommand.StreamOptions{
+		Stdin:  session,
+		Stdout: session.writer(TerminalOpStdout),
+		Tty:    opts.TTY,
+	}
+	if opts.TTY {
+		// A tty merges stderr into stdout
+		streamOptions.TerminalSizeQueue = session
+	} else {
+		streamOptions.Stderr = session.writer(TerminalOpStderr)
+	}
+
+	err = executor.Stream(streamOptions)
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("exec in %s/%s failed: %w", opts.Namespace, opts.Pod, err)
+	}
+	return nil
+}
+
+// podExecutor prepares an exec of opts.Command. A tty merges stderr into stdout so stderr is only requested without
+// one.
+func (kc *KubeCluster) podExecutor(opts ExecOptions, stdin bool) (remotecommand.Executor, error) {
+	coreclient, err := corev1client.NewForConfig(kc.restClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create coreclient for %s: %w", kc.name, err)
+	}
+
+	req := coreclient.RESTClient().Post().
+		Resource("pods").
+		Namespace(opts.Namespace).
+		Name(opts.Pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: opts.Container,
+			Command:   opts.Command,
+			Stdin:     stdin,
+			Stdout:    true,
+			Stderr:    !opts.TTY,
+			TTY:       opts.TTY,
+		}, scheme.ParameterCodec)
+
+	executor, err := kc.newExecutor(kc.restClientConfig, "POST", req.URL())
+	if err != nil {
+		return nil, fmt.Errorf("unable to create executor for %s/%s: %w", opts.Namespace, opts.Pod, err)
+	}
+	return executor, nil
+}
+
+// terminalSession adapts a TerminalConn to the io.Reader, io.Writer and remotecommand.TerminalSizeQueue that the
+// executor streams.
+type terminalSession struct {
+	conn      TerminalConn
+	sendLock  sync.Mutex
+	pending   []byte
+	sizes     chan remotecommand.TerminalSize
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newTerminalSession(conn TerminalConn) *terminalSession {
+	return &terminalSession{
+		conn:  conn,
+		sizes: make(chan remotecommand.TerminalSize, 1),
+		done:  make(chan struct{}),
+	}
+}
+
+// Read returns stdin from the client, handling any resize messages that arrive in between.
+func (s *terminalSession) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		msg, err := s.conn.Receive()
+		if err != nil {
+			// Closing the connection ends the remote command's stdin
+			return 0, io.EOF
+		}
+
+		switch msg.Op {
+		case TerminalOpStdin:
+			s.pending = []byte(msg.Data)
+		case TerminalOpResize:
+			s.resize(remotecommand.TerminalSize{Width: msg.Cols, Height: msg.Rows})
+		default:
+			return 0, fmt.Errorf("unknown terminal message op '%s'", msg.Op)
+		}
+	}
+
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// resize keeps only the most recent size if the executor hasn't caught up.
+func (s *terminalSession) resize(size remotecommand.TerminalSize) {
+	select {
+	case <-s.sizes:
+	default:
+	}
+	s.sizes <- size
+}
+
+// Next implements remotecommand.TerminalSizeQueue. nil ends the queue.
+func (s *terminalSession) Next() *remotecommand.TerminalSize {
+	select {
+	case size := <-s.sizes:
+		return &size
+	case <-s.done:
+		return nil
+	}
+}
+
+func (s *terminalSession) send(msg TerminalMessage) error {
+	s.sendLock.Lock()
+	defer s.sendLock.Unlock()
+	return s.conn.Send(msg)
+}
+
+func (s *terminalSession) writer(op string) io.Writer {
+	return &terminalWriter{session: s, op: op}
+}
+
+func (s *terminalSession) close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+type terminalWriter struct {
+	session *terminalSession
+	op      string
+}
+
+func (w *terminalWriter) Write(p []byte) (int, error) {
+	err := w.session.send(TerminalMessage{Op: w.op, Data: string(p)})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
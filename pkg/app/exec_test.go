@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// fakeConn plays a scripted client and records what the server sent.
+type fakeConn struct {
+	toServer []TerminalMessage
+	toClient []TerminalMessage
+}
+
+func (c *fakeConn) Receive() (TerminalMessage, error) {
+	if len(c.toServer) == 0 {
+		return TerminalMessage{}, io.EOF
+	}
+	msg := c.toServer[0]
+	c.toServer = c.toServer[1:]
+	return msg, nil
+}
+
+func (c *fakeConn) Send(msg TerminalMessage) error {
+	c.toClient = append(c.toClient, msg)
+	return nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+// idleConn is a client that never types. Receive blocks until it's closed.
+type idleConn struct {
+	closed chan struct{}
+}
+
+func (c *idleConn) Receive() (TerminalMessage, error) {
+	<-c.closed
+	return TerminalMessage{}, io.EOF
+}
+
+func (c *idleConn) Send(msg TerminalMessage) error {
+	return nil
+}
+
+func (c *idleConn) Close() error {
+	close(c.closed)
+	return nil
+}
+
+// echoExecutor copies stdin to stdout like `cat` and remembers the first terminal size.
+type echoExecutor struct {
+	url  *url.URL
+	size *remotecommand.TerminalSize
+}
+
+func (e *echoExecutor) Stream(options remotecommand.StreamOptions) error {
+	_, err := io.Copy(options.Stdout, options.Stdin)
+	if options.TerminalSizeQueue != nil {
+		e.size = options.TerminalSizeQueue.Next()
+	}
+	return err
+}
+
+func execTestCluster(executor *echoExecutor) *KubeCluster {
+	return &KubeCluster{
+		name:             "test",
+		restClientConfig: &restclient.Config{Host: "https://127.0.0.1:6443"},
+		newExecutor: func(config *restclient.Config, method string, u *url.URL) (remotecommand.Executor, error) {
+			executor.url = u
+			return executor, nil
+		},
+	}
+}
+
+func TestExecBridgesTerminalMessages(t *testing.T) {
+	executor := &echoExecutor{}
+	kc := execTestCluster(executor)
+
+	conn := &fakeConn{toServer: []TerminalMessage{
+		{Op: TerminalOpStdin, Data: "ls\n"},
+		{Op: TerminalOpResize, Cols: 120, Rows: 40},
+		{Op: TerminalOpStdin, Data: "exit\n"},
+	}}
+
+	opts := ExecOptions{Namespace: "back-end", Pod: "product-a", Container: "app", TTY: true}
+	if err := kc.Exec(context.Background(), opts, conn); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if executor.url.Path != "/api/v1/namespaces/back-end/pods/product-a/exec" {
+		t.Errorf("unexpected exec path %s", executor.url.Path)
+	}
+	query := executor.url.Query()
+	if query.Get("container") != "app" || query.Get("command") != "/bin/sh" || query.Get("tty") != "true" {
+		t.Errorf("unexpected exec query %s", executor.url.RawQuery)
+	}
+
+	var stdout strings.Builder
+	for _, msg := range conn.toClient {
+		if msg.Op != TerminalOpStdout {
+			t.Errorf("unexpected op %s", msg.Op)
+		}
+		stdout.WriteString(msg.Data)
+	}
+	if stdout.String() != "ls\nexit\n" {
+		t.Errorf("unexpected stdout %q", stdout.String())
+	}
+
+	if executor.size == nil || executor.size.Width != 120 || executor.size.Height != 40 {
+		t.Errorf("unexpected terminal size %+v", executor.size)
+	}
+}
+
+func TestExecCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	kc := execTestCluster(&echoExecutor{})
+
+	// The shell would wait on stdin forever, so only the cancel ends it
+	cancel()
+	err := kc.Exec(ctx, ExecOptions{Namespace: "back-end", Pod: "product-a"}, &idleConn{closed: make(chan struct{})})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
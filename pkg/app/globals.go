@@ -2,7 +2,11 @@ package app
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"sync"
+
+	"github.com/cheriot/kubenav/pkg/app/relations"
 )
 
 var kubeClustersLock = sync.RWMutex{}
@@ -24,3 +28,55 @@ func GetOrMakeKubeCluster(ctx context.Context, kubeCtxName string) (*KubeCluster
 
 	return kc, nil
 }
+
+// StopAllPortForwards stops the port-forwards of every cluster. Call it before the process exits.
+func StopAllPortForwards() {
+	kubeClustersLock.RLock()
+	defer kubeClustersLock.RUnlock()
+
+	for _, kc := range kubeClusters {
+		kc.StopAllPortForwards()
+	}
+}
+
+// RelationsConfigPath is $KUBENAV_RELATIONS or else relations.yaml in the user's kubenav config directory.
+func RelationsConfigPath() string {
+	if path := os.Getenv("KUBENAV_RELATIONS"); path != "" {
+		return path
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "kubenav", "relations.yaml")
+}
+
+// LoadRelationsConfig registers the user's declared relations. Call it once at startup.
+func LoadRelationsConfig() error {
+	path := RelationsConfigPath()
+	if path == "" {
+		return nil
+	}
+	return relations.LoadDeclaredRelations(path)
+}
+
+// CategoriesConfigPath is $KUBENAV_CATEGORIES or else categories.yaml in the user's kubenav config directory.
+func CategoriesConfigPath() string {
+	if path := os.Getenv("KUBENAV_CATEGORIES"); path != "" {
+		return path
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "kubenav", "categories.yaml")
+}
+
+// LoadCategoriesConfig registers the user's categories. Call it once at startup.
+func LoadCategoriesConfig() error {
+	path := CategoriesConfigPath()
+	if path == "" {
+		return nil
+	}
+	return LoadCategories(path)
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/cheriot/kubenav/pkg/app/relations"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// NamespaceGraph graphs the relations between the objects of a namespace. Kinds that can't be listed, ie forbidden,
+// are left out. A kind with more than LIST_LIMIT objects is an error since the graph would silently miss edges.
+func (kc *KubeCluster) NamespaceGraph(ctx context.Context, nsName string) (*relations.Graph, error) {
+	graph := relations.NewGraph()
+
+	objects := make(map[schema.GroupKind][]relations.Relatable)
+	for _, gk := range relations.GraphKinds() {
+		listed, errors := kc.listRelatable(ctx, gk, nsName)
+		for _, err := range errors {
+			if _, truncated := err.(*truncatedListError); truncated {
+				return nil, fmt.Errorf("unable to graph %s: %w", nsName, err)
+			}
+			log.Warnf("namespace graph of %s: %v", nsName, err)
+		}
+		objects[gk] = listed
+
+		for _, obj := range listed {
+			node, ok := graphDestination(gk, obj)
+			if !ok {
+				continue
+			}
+			graph.AddNode(node)
+			for _, owner := range relations.OwnerList(obj) {
+				graph.AddEdge(node, owner, relations.EdgeOwner, "")
+			}
+			for _, d := range relations.RelationsList(obj, gk) {
+				graph.AddEdge(node, d, relations.EdgeReference, d.Label)
+			}
+		}
+	}
+
+	for destinationGK, destinations := range objects {
+		for _, originGK := range relations.SelectingKinds(destinationGK) {
+			for _, destination := range destinations {
+				to, ok := graphDestination(destinationGK, destination)
+				if !ok {
+					continue
+				}
+				for _, from := range relations.SelectedBy(destination, destinationGK, originGK, objects[originGK]) {
+					graph.AddEdge(from, to, relations.EdgeSelects, "")
+				}
+			}
+		}
+	}
+
+	graph.Sort()
+	return graph, nil
+}
+
+// ObjectGraph graphs an object's neighborhood: its owners, children and the objects it references or is
+// referenced by.
+func (kc *KubeCluster) ObjectGraph(ctx context.Context, nsName string, kind string, resourceName string) (*relations.Graph, error) {
+	matches := findAPIResources(kc.apiResources, kind)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("unable to find an api resource: %s", kind)
+	}
+	apiResource := matches[0]
+
+	obj, err := kc.getResource(ctx, apiResource, nsName, resourceName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get %s %s/%s: %w", kind, nsName, resourceName, err)
+	}
+
+	graph := relations.NewGraph()
+	gk := toGK(apiResource)
+	center := relations.HasOneDestination{GroupKind: gk, Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	graph.AddNode(center)
+
+	for _, owner := range relations.OwnerList(obj) {
+		graph.AddEdge(center, owner, relations.EdgeOwner, "")
+	}
+	for _, d := range relations.RelationsList(obj, gk) {
+		graph.AddEdge(center, d, relations.EdgeReference, d.Label)
+	}
+
+	children, errors := kc.findChildren(ctx, obj, gk)
+	for _, child := range children {
+		graph.AddEdge(child, center, relations.EdgeOwner, "")
+	}
+	cache := make(relatableCache)
+	referencing, referencingErrors := kc.findReferencing(ctx, obj, apiResource, cache)
+	for _, origin := range referencing {
+		graph.AddEdge(origin, center, relations.EdgeReference, origin.Label)
+	}
+	selecting, selectingErrors := kc.findSelecting(ctx, obj, apiResource, cache)
+	for _, origin := range selecting {
+		graph.AddEdge(origin, center, relations.EdgeSelects, "")
+	}
+
+	errors = append(errors, referencingErrors...)
+	for _, err := range append(errors, selectingErrors...) {
+		log.Warnf("object graph of %s: %v", resourceName, err)
+	}
+
+	graph.Sort()
+	return graph, nil
+}
+
+// RenderGraph in one of relations.GraphJSON, GraphDOT or GraphMermaid.
+func RenderGraph(graph *relations.Graph, format string) (string, error) {
+	switch format {
+	case relations.GraphDOT:
+		return graph.DOT(), nil
+	case relations.GraphMermaid:
+		return graph.Mermaid(), nil
+	case relations.GraphJSON, "":
+		bytes, err := json.MarshalIndent(graph, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("unable to marshal graph: %w", err)
+		}
+		return string(bytes), nil
+	}
+	return "", fmt.Errorf("unknown graph format %s, expected json, dot or mermaid", format)
+}
+
+func graphDestination(gk schema.GroupKind, obj relations.Relatable) (relations.HasOneDestination, bool) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return relations.HasOneDestination{}, false
+	}
+	return relations.HasOneDestination{GroupKind: gk, Namespace: accessor.GetNamespace(), Name: accessor.GetName()}, true
+}
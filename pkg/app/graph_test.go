@@ -0,0 +1,21 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNamespaceGraphTruncated(t *testing.T) {
+	kc, client := fakeCluster(t, deploymentObjects()...)
+	graph, err := kc.NamespaceGraph(context.Background(), "back-end")
+	if err != nil || len(graph.Nodes) != 6 {
+		t.Fatalf("expected a graph of the deployment, got %+v %v", graph, err)
+	}
+
+	kc.dynamicClient = truncatingClient{Interface: client, resource: "pods"}
+	_, err = kc.NamespaceGraph(context.Background(), "back-end")
+	if err == nil || !strings.Contains(err.Error(), "only the first 1000 Pod in back-end were listed") {
+		t.Errorf("expected a truncated pods error, got %v", err)
+	}
+}
@@ -0,0 +1,349 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/cheriot/kubenav/pkg/app/relations"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type HealthStatus string
+
+const (
+	Healthy     HealthStatus = "Healthy"
+	Progressing HealthStatus = "Progressing"
+	Degraded    HealthStatus = "Degraded"
+	Unknown     HealthStatus = "Unknown"
+)
+
+type Health struct {
+	Status HealthStatus `json:"status"`
+	// Why it isn't healthy. ie "container app: CrashLoopBackOff, 12 restarts"
+	Reasons []string `json:"reasons"`
+}
+
+// HealthCheck computes the health of one kind. obj is the kind's go type when relations.Typed has one, otherwise
+// unstructured.
+type HealthCheck func(obj runtime.Object) Health
+
+var healthChecks = map[schema.GroupKind]HealthCheck{
+	{Group: "apps", Kind: "Deployment"}:  typedHealth(deploymentHealth),
+	{Group: "apps", Kind: "StatefulSet"}: typedHealth(statefulSetHealth),
+	{Group: "apps", Kind: "DaemonSet"}:   typedHealth(daemonSetHealth),
+	{Kind: "Pod"}:                        typedHealth(podHealth),
+	{Kind: "PersistentVolumeClaim"}:      typedHealth(pvcHealth),
+	{Kind: "Node"}:                       typedHealth(nodeHealth),
+	{Group: "batch", Kind: "Job"}:        typedHealth(jobHealth),
+}
+
+// RegisterHealthCheck overrides the health of a kind. Kinds without a check, ie most custom resources, get their
+// health from status.conditions. Register before serving requests.
+func RegisterHealthCheck(gk schema.GroupKind, check HealthCheck) {
+	healthChecks[gk] = check
+}
+
+// ObjectHealth of obj, which may be a go type or unstructured.
+func ObjectHealth(obj runtime.Object, gk schema.GroupKind) Health {
+	if check, found := healthChecks[gk]; found {
+		return check(obj)
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return unknownHealth(fmt.Sprintf("unable to read status: %s", err.Error()))
+		}
+		u = &unstructured.Unstructured{Object: content}
+	}
+	return conditionsHealth(u)
+}
+
+func typedHealth[T runtime.Object](check func(T) Health) HealthCheck {
+	return func(obj runtime.Object) Health {
+		typed, err := relations.Typed(obj)
+		if err != nil {
+			return unknownHealth(err.Error())
+		}
+		t, ok := typed.(T)
+		if !ok {
+			return unknownHealth(fmt.Sprintf("unexpected type %T", typed))
+		}
+		return check(t)
+	}
+}
+
+func unknownHealth(reason string) Health {
+	return Health{Status: Unknown, Reasons: []string{reason}}
+}
+
+// healthBuilder keeps the worst status seen.
+type healthBuilder struct {
+	Health
+}
+
+func newHealthBuilder() *healthBuilder {
+	return &healthBuilder{Health{Status: Healthy, Reasons: make([]string, 0)}}
+}
+
+var healthSeverity = map[HealthStatus]int{Healthy: 0, Unknown: 1, Progressing: 2, Degraded: 3}
+
+func (hb *healthBuilder) add(status HealthStatus, format string, args ...interface{}) {
+	if healthSeverity[status] > healthSeverity[hb.Status] {
+		hb.Status = status
+	}
+	hb.Reasons = append(hb.Reasons, fmt.Sprintf(format, args...))
+}
+
+// Conditions commonly used to report readiness, in order of preference
+var readyConditionTypes = []string{"Ready", "Available", "Succeeded", "Synced", "Healthy"}
+
+// conditionsHealth follows the status.conditions convention: the first ready-like condition decides, and any
+// condition with a False ready-like status explains why. Kinds without a status, ie ConfigMaps and Secrets, have
+// nothing to be unhealthy about.
+func conditionsHealth(u *unstructured.Unstructured) Health {
+	status, found, err := unstructured.NestedFieldNoCopy(u.Object, "status")
+	if err == nil && (!found || isEmptyStatus(status)) {
+		return Health{Status: Healthy, Reasons: []string{}}
+	}
+
+	conditions, found, err := unstructured.NestedSlice(u.Object, "status", "conditions")
+	if err != nil || !found || len(conditions) == 0 {
+		return Health{Status: Unknown, Reasons: []string{"no status.conditions"}}
+	}
+
+	byType := make(map[string]map[string]interface{})
+	for _, c := range conditions {
+		if condition, ok := c.(map[string]interface{}); ok {
+			if t, ok := condition["type"].(string); ok {
+				byType[t] = condition
+			}
+		}
+	}
+
+	for _, t := range readyConditionTypes {
+		condition, found := byType[t]
+		if !found {
+			continue
+		}
+		status, _ := condition["status"].(string)
+		reason, _ := condition["reason"].(string)
+		message, _ := condition["message"].(string)
+		switch metav1.ConditionStatus(status) {
+		case metav1.ConditionTrue:
+			return Health{Status: Healthy, Reasons: []string{}}
+		case metav1.ConditionFalse:
+			return Health{Status: Degraded, Reasons: []string{conditionReason(t, reason, message)}}
+		default:
+			return Health{Status: Progressing, Reasons: []string{conditionReason(t, reason, message)}}
+		}
+	}
+	return Health{Status: Unknown, Reasons: []string{"no Ready or Available condition"}}
+}
+
+// isEmptyStatus if it has no values, only empty maps and lists. ie a Service's status of `loadBalancer: {}`
+func isEmptyStatus(status interface{}) bool {
+	switch v := status.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		for _, field := range v {
+			if !isEmptyStatus(field) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func conditionReason(conditionType string, reason string, message string) string {
+	switch {
+	case reason != "" && message != "":
+		return fmt.Sprintf("%s: %s, %s", conditionType, reason, message)
+	case reason != "":
+		return fmt.Sprintf("%s: %s", conditionType, reason)
+	case message != "":
+		return fmt.Sprintf("%s: %s", conditionType, message)
+	}
+	return fmt.Sprintf("%s is not true", conditionType)
+}
+
+func deploymentHealth(d *appsv1.Deployment) Health {
+	hb := newHealthBuilder()
+	if d.Generation > d.Status.ObservedGeneration {
+		hb.add(Progressing, "rollout of generation %d not yet observed", d.Generation)
+	}
+
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	for _, c := range d.Status.Conditions {
+		switch {
+		case c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded":
+			hb.add(Degraded, "rollout stalled: %s", c.Message)
+		case c.Type == appsv1.DeploymentReplicaFailure && c.Status == corev1.ConditionTrue:
+			hb.add(Degraded, "replica failure: %s", c.Message)
+		// Usual while a rollout or scale up waits for pods. It's ProgressDeadlineExceeded once that takes too long.
+		case c.Type == appsv1.DeploymentAvailable && c.Status == corev1.ConditionFalse && c.Reason == "MinimumReplicasUnavailable":
+			hb.add(Progressing, "unavailable: %s", c.Message)
+		case c.Type == appsv1.DeploymentAvailable && c.Status == corev1.ConditionFalse:
+			hb.add(Degraded, "unavailable: %s", c.Message)
+		}
+	}
+
+	if d.Status.UpdatedReplicas < replicas {
+		hb.add(Progressing, "%d of %d replicas updated", d.Status.UpdatedReplicas, replicas)
+	}
+	if d.Status.AvailableReplicas < replicas {
+		hb.add(Progressing, "%d of %d replicas available", d.Status.AvailableReplicas, replicas)
+	}
+	if d.Status.Replicas > replicas {
+		hb.add(Progressing, "%d old replicas pending termination", d.Status.Replicas-replicas)
+	}
+	return hb.Health
+}
+
+func statefulSetHealth(sts *appsv1.StatefulSet) Health {
+	hb := newHealthBuilder()
+	if sts.Generation > sts.Status.ObservedGeneration {
+		hb.add(Progressing, "rollout of generation %d not yet observed", sts.Generation)
+	}
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+	if sts.Status.UpdatedReplicas < replicas {
+		hb.add(Progressing, "%d of %d replicas updated", sts.Status.UpdatedReplicas, replicas)
+	}
+	if sts.Status.ReadyReplicas < replicas {
+		hb.add(Progressing, "%d of %d replicas ready", sts.Status.ReadyReplicas, replicas)
+	}
+	return hb.Health
+}
+
+func daemonSetHealth(ds *appsv1.DaemonSet) Health {
+	hb := newHealthBuilder()
+	if ds.Generation > ds.Status.ObservedGeneration {
+		hb.add(Progressing, "rollout of generation %d not yet observed", ds.Generation)
+	}
+	if ds.Status.UpdatedNumberScheduled < ds.Status.DesiredNumberScheduled {
+		hb.add(Progressing, "%d of %d pods updated", ds.Status.UpdatedNumberScheduled, ds.Status.DesiredNumberScheduled)
+	}
+	if ds.Status.NumberUnavailable > 0 {
+		hb.add(Progressing, "%d of %d pods unavailable", ds.Status.NumberUnavailable, ds.Status.DesiredNumberScheduled)
+	}
+	if ds.Status.NumberMisscheduled > 0 {
+		hb.add(Degraded, "%d pods running where they shouldn't", ds.Status.NumberMisscheduled)
+	}
+	return hb.Health
+}
+
+// Container waiting reasons that won't resolve without a change
+var degradedWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+}
+
+func podHealth(pod *corev1.Pod) Health {
+	hb := newHealthBuilder()
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded:
+		return hb.Health
+	case corev1.PodFailed:
+		hb.add(Degraded, "failed: %s %s", pod.Status.Reason, pod.Status.Message)
+		return hb.Health
+	case corev1.PodUnknown:
+		hb.add(Unknown, "the node stopped reporting the pod's status")
+		return hb.Health
+	}
+
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse {
+			hb.add(Degraded, "unschedulable: %s", c.Message)
+		}
+	}
+
+	statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		switch {
+		case cs.State.Waiting != nil && degradedWaitingReasons[cs.State.Waiting.Reason]:
+			hb.add(Degraded, "container %s: %s, %d restarts", cs.Name, cs.State.Waiting.Reason, cs.RestartCount)
+		case cs.State.Terminated != nil && cs.State.Terminated.Reason == "OOMKilled":
+			hb.add(Degraded, "container %s: OOMKilled", cs.Name)
+		case cs.State.Waiting != nil:
+			hb.add(Progressing, "container %s: %s", cs.Name, cs.State.Waiting.Reason)
+		}
+	}
+
+	if pod.Status.Phase == corev1.PodPending && len(hb.Reasons) == 0 {
+		hb.add(Progressing, "pending")
+	}
+	if pod.Status.Phase == corev1.PodRunning && !isPodReady(pod) && hb.Status == Healthy {
+		hb.add(Progressing, "running but not ready")
+	}
+	return hb.Health
+}
+
+func pvcHealth(pvc *corev1.PersistentVolumeClaim) Health {
+	hb := newHealthBuilder()
+	switch pvc.Status.Phase {
+	case corev1.ClaimPending:
+		// Normal until a pod is scheduled with a WaitForFirstConsumer storage class
+		hb.add(Progressing, "waiting to be bound to a volume")
+	case corev1.ClaimLost:
+		hb.add(Degraded, "lost its volume %s", pvc.Spec.VolumeName)
+	}
+	return hb.Health
+}
+
+func nodeHealth(node *corev1.Node) Health {
+	hb := newHealthBuilder()
+	for _, c := range node.Status.Conditions {
+		switch {
+		case c.Type == corev1.NodeReady && c.Status != corev1.ConditionTrue:
+			hb.add(Degraded, "NotReady: %s", c.Message)
+		case c.Type != corev1.NodeReady && c.Status == corev1.ConditionTrue:
+			// MemoryPressure, DiskPressure, PIDPressure, NetworkUnavailable
+			hb.add(Degraded, "%s: %s", c.Type, c.Message)
+		}
+	}
+	return hb.Health
+}
+
+func jobHealth(job *batchv1.Job) Health {
+	hb := newHealthBuilder()
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			return hb.Health
+		case batchv1.JobFailed:
+			hb.add(Degraded, "failed: %s %s", c.Reason, c.Message)
+			return hb.Health
+		}
+	}
+	if job.Spec.Suspend != nil && *job.Spec.Suspend {
+		hb.add(Progressing, "suspended")
+		return hb.Health
+	}
+	hb.add(Progressing, "%d active, %d succeeded, %d failed", job.Status.Active, job.Status.Succeeded, job.Status.Failed)
+	return hb.Health
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestObjectHealth(t *testing.T) {
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "product", Namespace: "back-end", Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 3,
+		},
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(deployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scalingUp := deployment.DeepCopy()
+	scalingUp.Status = appsv1.DeploymentStatus{
+		ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 2,
+		Conditions: []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionFalse, Reason: "MinimumReplicasUnavailable", Message: "Deployment does not have minimum availability."},
+			{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue, Reason: "ReplicaSetUpdated"},
+		},
+	}
+	stalled := scalingUp.DeepCopy()
+	stalled.Status.Conditions[1] = appsv1.DeploymentCondition{
+		Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded", Message: "ReplicaSet \"product-5d8f\" has timed out progressing.",
+	}
+
+	crashing := &corev1.Pod{
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "app", RestartCount: 12,
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			}},
+		},
+	}
+	failedJob := &batchv1.Job{Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+		{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded", Message: "Job has reached the specified backoff limit"},
+	}}}
+	notReady := &corev1.Node{Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+		{Type: corev1.NodeReady, Status: corev1.ConditionUnknown, Message: "Kubelet stopped posting node status."},
+		{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+	}}}
+	certificate := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "Certificate",
+		"status": map[string]interface{}{"conditions": []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "False", "reason": "DoesNotExist", "message": "Issuing certificate"},
+		}},
+	}}
+
+	cases := []struct {
+		name     string
+		obj      runtime.Object
+		gk       schema.GroupKind
+		expected Health
+	}{
+		{"deployment rolling out", &unstructured.Unstructured{Object: content}, deployment.GroupVersionKind().GroupKind(),
+			Health{Status: Progressing, Reasons: []string{"1 of 3 replicas updated"}}},
+		{"crash loop", crashing, podGK,
+			Health{Status: Degraded, Reasons: []string{"container app: CrashLoopBackOff, 12 restarts"}}},
+		{"unbound pvc", &corev1.PersistentVolumeClaim{Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending}}, schema.GroupKind{Kind: "PersistentVolumeClaim"},
+			Health{Status: Progressing, Reasons: []string{"waiting to be bound to a volume"}}},
+		{"failed job", failedJob, schema.GroupKind{Group: "batch", Kind: "Job"},
+			Health{Status: Degraded, Reasons: []string{"failed: BackoffLimitExceeded Job has reached the specified backoff limit"}}},
+		{"node not ready", notReady, schema.GroupKind{Kind: "Node"},
+			Health{Status: Degraded, Reasons: []string{"NotReady: Kubelet stopped posting node status."}}},
+		{"custom resource conditions", certificate, schema.GroupKind{Group: "cert-manager.io", Kind: "Certificate"},
+			Health{Status: Degraded, Reasons: []string{"Ready: DoesNotExist, Issuing certificate"}}},
+		{"custom resource without status", &unstructured.Unstructured{Object: map[string]interface{}{}}, schema.GroupKind{Group: "example.com", Kind: "Widget"},
+			Health{Status: Healthy, Reasons: []string{}}},
+		{"custom resource without conditions", &unstructured.Unstructured{Object: map[string]interface{}{"status": map[string]interface{}{"phase": "Pending"}}}, schema.GroupKind{Group: "example.com", Kind: "Widget"},
+			Health{Status: Unknown, Reasons: []string{"no status.conditions"}}},
+		{"config map", &corev1.ConfigMap{Data: map[string]string{"key": "value"}}, schema.GroupKind{Kind: "ConfigMap"},
+			Health{Status: Healthy, Reasons: []string{}}},
+		{"service", &corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP}}, schema.GroupKind{Kind: "Service"},
+			Health{Status: Healthy, Reasons: []string{}}},
+		{"deployment scaling up", scalingUp, deployment.GroupVersionKind().GroupKind(),
+			Health{Status: Progressing, Reasons: []string{"unavailable: Deployment does not have minimum availability.", "2 of 3 replicas available"}}},
+		{"deployment stalled", stalled, deployment.GroupVersionKind().GroupKind(),
+			Health{Status: Degraded, Reasons: []string{"unavailable: Deployment does not have minimum availability.", "rollout stalled: ReplicaSet \"product-5d8f\" has timed out progressing.", "2 of 3 replicas available"}}},
+	}
+
+	for _, c := range cases {
+		actual := ObjectHealth(c.obj, c.gk)
+		if !reflect.DeepEqual(c.expected, actual) {
+			t.Errorf("%s: expected %+v, got %+v", c.name, c.expected, actual)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
@@ -22,8 +23,10 @@ import (
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/metadata"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
 	"k8s.io/client-go/util/homedir"
 	"k8s.io/kubectl/pkg/describe"
 
@@ -47,6 +50,10 @@ type KubeCluster struct {
 	apiResources     []metav1.APIResource
 	scheme           *runtime.Scheme // Could be global since it's go types?
 	dynamicClient    dynamic.Interface
+	metadataClient   metadata.Interface
+	newExecutor      ExecutorFactory
+	portForwards     *portForwardRegistry
+	metrics          MetricsClient
 }
 
 func NewKubeClusterDefault(ctx context.Context) (*KubeCluster, error) {
@@ -71,6 +78,11 @@ func NewKubeCluster(ctx context.Context, kubeCtxName string) (*KubeCluster, erro
 		return nil, fmt.Errorf("error creating dynamicClient: %w", err)
 	}
 
+	metadataClient, err := metadata.NewForConfig(restClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating metadataClient: %w", err)
+	}
+
 	apiResource, err := fetchAllApiResources(restClientConfig)
 	if err != nil {
 		return nil, fmt.Errorf("error getting api-resources: %w", err)
@@ -89,6 +101,10 @@ func NewKubeCluster(ctx context.Context, kubeCtxName string) (*KubeCluster, erro
 		apiResources:     apiResource,
 		scheme:           scheme,
 		dynamicClient:    dynamicClient,
+		metadataClient:   metadataClient,
+		newExecutor:      remotecommand.NewSPDYExecutor,
+		portForwards:     newPortForwardRegistry(),
+		metrics:          newMetricsClient(apiResource, dynamicClient),
 	}, nil
 }
 
@@ -200,17 +216,34 @@ type ResourceTable struct {
 	Table         *metav1.Table      `json:"table"`
 	IsError       bool               `json:"isError"`
 	TableRowNames []string           `json:"tableRowNames"`
+	// How long listing and printing took
+	LatencyMillis int64 `json:"latencyMillis"`
 }
 
 func (kc *KubeCluster) Query(ctx context.Context, nsName string, query string) ([]ResourceTable, error) {
-	log.Infof("Query for %s", query)
+	return kc.QueryWithParams(ctx, nsName, query, nil)
+}
+
+// QueryWithParams narrows Query with the params of a relations.HasManyDestination.
+func (kc *KubeCluster) QueryWithParams(ctx context.Context, nsName string, query string, params map[string]string) ([]ResourceTable, error) {
+	log.Infof("Query for %s %v", query, params)
 	matches := findAPIResources(kc.apiResources, query)
 	log.Infof("matches %v", util.Map(matches, func(ar metav1.APIResource) string { return ar.Kind }))
 
-	results := util.Map(matches, func(r metav1.APIResource) ResourceTable {
-		table, err := kc.listResource(ctx, r, nsName)
+	listOptions := metav1.ListOptions{
+		LabelSelector: params[relations.LabelSelectorParam],
+		FieldSelector: params[relations.FieldSelectorParam],
+	}
+
+	// A category like `all` matches dozens of resources. List them concurrently, but not all at once.
+	results := util.MapConcurrent(matches, QUERY_WORKERS, func(r metav1.APIResource) ResourceTable {
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(ctx, QUERY_RESOURCE_TIMEOUT)
+		defer cancel()
+
+		table, rowNamespaces, err := kc.listResource(ctx, r, nsName, listOptions)
 		if err != nil {
-			log.Errorf("listResource error for resource %+v: %w", r, err)
+			log.Errorf("listResource error for resource %+v: %v", r, err)
 			table = PrintError(err)
 		}
 
@@ -232,11 +265,21 @@ func (kc *KubeCluster) Query(ctx context.Context, nsName string, query string) (
 			}
 		}
 
+		// Every printer has a row per object, but don't index past the end if one doesn't
+		if err == nil && len(rowNamespaces) == len(rowNames) {
+			if usage := kc.usageByName(ctx, r, nsName); usage != nil {
+				addUsageColumns(table, rowNamespaces, rowNames, usage)
+			}
+		}
+
+		latency := time.Since(start)
+		log.Debugf("Query of %s took %v", r.Name, latency)
 		return ResourceTable{
 			APIResource:   r,
 			Table:         table,
 			IsError:       err != nil,
 			TableRowNames: rowNames,
+			LatencyMillis: latency.Milliseconds(),
 		}
 	})
 
@@ -250,6 +293,10 @@ func (kc *KubeCluster) Query(ctx context.Context, nsName string, query string) (
 }
 
 func findAPIResources(apiResources []metav1.APIResource, identifier string) []metav1.APIResource {
+	if expanded, isCategory := expandCategory(apiResources, identifier); isCategory {
+		return expanded
+	}
+
 	isMatch := func(r metav1.APIResource) bool {
 		names := []string{
 			strings.ToLower(r.Name),
@@ -257,7 +304,6 @@ func findAPIResources(apiResources []metav1.APIResource, identifier string) []me
 			strings.ToLower(r.Group),
 			strings.ToLower(r.SingularName),
 		}
-		names = append(names, r.Categories...)
 		names = append(names, r.ShortNames...)
 		return util.Contains(names, strings.ToLower(identifier))
 	}
@@ -265,11 +311,31 @@ func findAPIResources(apiResources []metav1.APIResource, identifier string) []me
 	return util.Filter(apiResources, isMatch)
 }
 
+func findAPIResourceByGK(apiResources []metav1.APIResource, gk schema.GroupKind) (metav1.APIResource, bool) {
+	for _, r := range apiResources {
+		if toGK(r) == gk {
+			return r, true
+		}
+	}
+	return metav1.APIResource{}, false
+}
+
 type KubeObject struct {
-	Relations []relations.HasOneDestination `json:"relations"`
-	Describe  string                        `json:"describe"`
-	Yaml      string                        `json:"yaml"`
-	Errors    []error                       `json:"errors"`
+	Relations []relations.HasOneDestination  `json:"relations"`
+	HasMany   []relations.HasManyDestination `json:"hasMany"`
+	// Objects with an ownerReference to this one
+	Children []relations.HasOneDestination `json:"children"`
+	// Objects with a relation to this one. ie the pods that mount a ConfigMap
+	ReferencedBy []relations.HasOneDestination `json:"referencedBy"`
+	Health       Health                        `json:"health"`
+	// HorizontalPodAutoscalers and PodDisruptionBudgets of a workload with their replica counts
+	Scaling []ScalingObject `json:"scaling"`
+	// A pod's containers' usage from the metrics API, if it's served
+	Usage    []ContainerUsage   `json:"usage"`
+	Actions  []KubeObjectAction `json:"actions"`
+	Describe string             `json:"describe"`
+	Yaml     string             `json:"yaml"`
+	Errors   []error            `json:"errors"`
 }
 
 func (kc *KubeCluster) GetResource(ctx context.Context, nsName string, kind string, resourceName string) (*KubeObject, error) {
@@ -299,27 +365,43 @@ func (kc *KubeCluster) GetResource(ctx context.Context, nsName string, kind stri
 		errors = append(errors, fmt.Errorf("unable to describe: %w", err))
 	}
 
-	rs := make([]relations.HasOneDestination, 0)
-	if kc.scheme.IsGroupRegistered(apiResource.Group) {
-		gvk := toGVK(apiResource)
-		obj, err := kc.scheme.New(gvk)
-		if err != nil {
-			errors = append(errors, fmt.Errorf("unable to instantiate %s when yamling %s %s %s", gvk, nsName, kind, resourceName))
-		} else {
+	// Owners come from metadata so they're available for every kind
+	rs := relations.OwnerList(unstructured)
+	var origin relations.Relatable = unstructured
+	if typed, err := relations.Typed(unstructured); err != nil {
+		// The relations that only need metadata and field paths still apply
+		errors = append(errors, err)
+	} else {
+		origin = typed
+	}
+	rs = append(rs, relations.RelationsList(origin, toGK(apiResource))...)
+	hasMany := relations.HasManyList(origin, toGK(apiResource))
 
-			err = kc.scheme.Convert(unstructured, obj, nil)
-			if err != nil {
-				errors = append(errors, fmt.Errorf("unable to convert: %w", err))
-			}
-			rs = relations.RelationsList(obj, toGK(apiResource))
-		}
+	children, childErrors := kc.findChildren(ctx, unstructured, toGK(apiResource))
+	errors = append(errors, childErrors...)
+
+	listed := make(relatableCache)
+	referencedBy, referencedByErrors := kc.findReferencedBy(ctx, unstructured, apiResource, listed)
+	errors = append(errors, referencedByErrors...)
+	scaling := kc.findScaling(referencedBy, listed)
+
+	var usage []ContainerUsage
+	if pod, ok := origin.(*corev1.Pod); ok {
+		usage = kc.findContainerUsage(ctx, pod)
 	}
 
 	return &KubeObject{
-		Relations: rs,
-		Yaml:      yamlStr,
-		Describe:  describeStr,
-		Errors:    errors,
+		Relations:    rs,
+		HasMany:      hasMany,
+		Children:     children,
+		ReferencedBy: referencedBy,
+		Health:       ObjectHealth(origin, toGK(apiResource)),
+		Scaling:      scaling,
+		Usage:        usage,
+		Actions:      objectActions(toGK(apiResource), unstructured),
+		Yaml:         yamlStr,
+		Describe:     describeStr,
+		Errors:       errors,
 	}, nil
 }
 
@@ -449,7 +531,7 @@ func fetchAllApiResources(restClientConfig *restclient.Config) ([]metav1.APIReso
 			for _, r := range rls.APIResources {
 				group, version, err := splitGroupVersion(rls.GroupVersion)
 				if err != nil {
-					log.Errorf("error splitting GroupVersion on %+v: %w", rls, err)
+					log.Errorf("error splitting GroupVersion on %+v: %v", rls, err)
 					continue
 				}
 				r.Group = group
@@ -493,19 +575,86 @@ func isSubresource(r metav1.APIResource) bool {
 
 const LIST_LIMIT = 1000
 
-func (kc *KubeCluster) listResource(ctx context.Context, r metav1.APIResource, namespace string) (*metav1.Table, error) {
+const (
+	// Concurrent list calls per Query
+	QUERY_WORKERS = 8
+	// A slow resource, ie an aggregated API that's down, shows an error instead of holding up the rest
+	QUERY_RESOURCE_TIMEOUT = 10 * time.Second
+)
+
+// listResource prints r's list. The printers leave out the namespace, so it's returned for each row.
+func (kc *KubeCluster) listResource(ctx context.Context, r metav1.APIResource, namespace string, opts metav1.ListOptions) (*metav1.Table, []string, error) {
+	uList, err := kc.listUnstructured(ctx, r, namespace, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	table, err := PrintList(kc.scheme, r, uList)
+	if err != nil {
+		return nil, nil, err
+	}
+	return table, util.Map(uList.Items, func(u unstructured.Unstructured) string { return u.GetNamespace() }), nil
+}
+
+// listUnstructured lists r in namespace, or in all namespaces if namespace is empty.
+func (kc *KubeCluster) listUnstructured(ctx context.Context, r metav1.APIResource, namespace string, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	opts.Limit = LIST_LIMIT
+
 	var uList *unstructured.UnstructuredList
 	var err error
 	if r.Namespaced {
-		uList, err = kc.dynamicClient.Resource(toGVR(r)).Namespace(namespace).List(ctx, metav1.ListOptions{Limit: LIST_LIMIT})
+		uList, err = kc.dynamicClient.Resource(toGVR(r)).Namespace(namespace).List(ctx, opts)
 	} else {
-		uList, err = kc.dynamicClient.Resource(toGVR(r)).List(ctx, metav1.ListOptions{Limit: LIST_LIMIT})
+		uList, err = kc.dynamicClient.Resource(toGVR(r)).List(ctx, opts)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("dynamicClient list failed for %+v: %w", r, err)
 	}
 
-	return PrintList(kc.scheme, r, uList)
+	return uList, nil
+}
+
+// listMetadata is listUnstructured without the spec and status, for when only names or a count are needed.
+func (kc *KubeCluster) listMetadata(ctx context.Context, r metav1.APIResource, namespace string, opts metav1.ListOptions) (*metav1.PartialObjectMetadataList, error) {
+	opts.Limit = LIST_LIMIT
+
+	var mList *metav1.PartialObjectMetadataList
+	var err error
+	if r.Namespaced {
+		mList, err = kc.metadataClient.Resource(toGVR(r)).Namespace(namespace).List(ctx, opts)
+	} else {
+		mList, err = kc.metadataClient.Resource(toGVR(r)).List(ctx, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("metadataClient list failed for %+v: %w", r, err)
+	}
+
+	return mList, nil
+}
+
+// isTruncated if the list stopped at LIST_LIMIT and there are more objects to list.
+func isTruncated(uList *unstructured.UnstructuredList) bool {
+	return uList.GetContinue() != ""
+}
+
+// truncatedListError is for results built from a list that isTruncated. Whatever wasn't listed is missing from them.
+type truncatedListError struct {
+	gk     schema.GroupKind
+	nsName string
+}
+
+func (e *truncatedListError) Error() string {
+	return fmt.Sprintf("only the first %d %s in %s were listed", LIST_LIMIT, e.gk, e.nsName)
+}
+
+// onlyTruncated if every error is a truncatedListError, ie the objects that were listed can still be used.
+func onlyTruncated(errors []error) bool {
+	for _, err := range errors {
+		if _, ok := err.(*truncatedListError); !ok {
+			return false
+		}
+	}
+	return true
 }
 
 func (kc *KubeCluster) getResource(ctx context.Context, r metav1.APIResource, namespace string, name string) (*unstructured.Unstructured, error) {
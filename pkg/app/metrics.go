@@ -0,0 +1,262 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	podMetricsGK  = schema.GroupKind{Group: "metrics.k8s.io", Kind: "PodMetrics"}
+	nodeMetricsGK = schema.GroupKind{Group: "metrics.k8s.io", Kind: "NodeMetrics"}
+	nodeGK        = schema.GroupKind{Kind: "Node"}
+)
+
+// MetricsClient reads current usage from the metrics API, ie metrics-server. KubeCluster's is nil when the cluster
+// doesn't serve metrics.k8s.io.
+type MetricsClient interface {
+	ListPodMetrics(ctx context.Context, nsName string) ([]PodMetrics, error)
+	GetPodMetrics(ctx context.Context, nsName string, name string) (*PodMetrics, error)
+	ListNodeMetrics(ctx context.Context) ([]NodeMetrics, error)
+}
+
+type PodMetrics struct {
+	Namespace  string             `json:"namespace"`
+	Name       string             `json:"name"`
+	Containers []ContainerMetrics `json:"containers"`
+}
+
+type ContainerMetrics struct {
+	Name  string              `json:"name"`
+	Usage corev1.ResourceList `json:"usage"`
+}
+
+type NodeMetrics struct {
+	Name  string              `json:"name"`
+	Usage corev1.ResourceList `json:"usage"`
+}
+
+// ContainerUsage compares a container's usage to its requests and limits. Percents are 0 when there's no request or
+// limit.
+type ContainerUsage struct {
+	Name   string        `json:"name"`
+	CPU    ResourceUsage `json:"cpu"`
+	Memory ResourceUsage `json:"memory"`
+}
+
+type ResourceUsage struct {
+	Usage          resource.Quantity `json:"usage"`
+	Request        resource.Quantity `json:"request"`
+	Limit          resource.Quantity `json:"limit"`
+	RequestPercent int64             `json:"requestPercent"`
+	LimitPercent   int64             `json:"limitPercent"`
+}
+
+// newMetricsClient if metrics.k8s.io was discovered.
+func newMetricsClient(apiResources []metav1.APIResource, dynamicClient dynamic.Interface) MetricsClient {
+	pods, podsFound := findAPIResourceByGK(apiResources, podMetricsGK)
+	nodes, nodesFound := findAPIResourceByGK(apiResources, nodeMetricsGK)
+	if !podsFound || !nodesFound {
+		return nil
+	}
+	return &dynamicMetricsClient{dynamicClient: dynamicClient, pods: toGVR(pods), nodes: toGVR(nodes)}
+}
+
+// dynamicMetricsClient reads the metrics API as unstructured so it doesn't need a typed clientset.
+type dynamicMetricsClient struct {
+	dynamicClient dynamic.Interface
+	pods          schema.GroupVersionResource
+	nodes         schema.GroupVersionResource
+}
+
+func (mc *dynamicMetricsClient) ListPodMetrics(ctx context.Context, nsName string) ([]PodMetrics, error) {
+	uList, err := mc.dynamicClient.Resource(mc.pods).Namespace(nsName).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list pod metrics in %s: %w", nsName, err)
+	}
+	metrics := make([]PodMetrics, 0, len(uList.Items))
+	for i := range uList.Items {
+		metrics = append(metrics, toPodMetrics(&uList.Items[i]))
+	}
+	return metrics, nil
+}
+
+func (mc *dynamicMetricsClient) GetPodMetrics(ctx context.Context, nsName string, name string) (*PodMetrics, error) {
+	u, err := mc.dynamicClient.Resource(mc.pods).Namespace(nsName).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get pod metrics for %s/%s: %w", nsName, name, err)
+	}
+	metrics := toPodMetrics(u)
+	return &metrics, nil
+}
+
+func (mc *dynamicMetricsClient) ListNodeMetrics(ctx context.Context) ([]NodeMetrics, error) {
+	uList, err := mc.dynamicClient.Resource(mc.nodes).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list node metrics: %w", err)
+	}
+	metrics := make([]NodeMetrics, 0, len(uList.Items))
+	for _, u := range uList.Items {
+		usage, _, _ := unstructured.NestedMap(u.Object, "usage")
+		metrics = append(metrics, NodeMetrics{Name: u.GetName(), Usage: toResourceList(usage)})
+	}
+	return metrics, nil
+}
+
+func toPodMetrics(u *unstructured.Unstructured) PodMetrics {
+	metrics := PodMetrics{Namespace: u.GetNamespace(), Name: u.GetName(), Containers: make([]ContainerMetrics, 0)}
+	containers, _, _ := unstructured.NestedSlice(u.Object, "containers")
+	for _, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(container, "name")
+		usage, _, _ := unstructured.NestedMap(container, "usage")
+		metrics.Containers = append(metrics.Containers, ContainerMetrics{Name: name, Usage: toResourceList(usage)})
+	}
+	return metrics
+}
+
+func toResourceList(usage map[string]interface{}) corev1.ResourceList {
+	rl := make(corev1.ResourceList, len(usage))
+	for name, value := range usage {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if q, err := resource.ParseQuantity(s); err == nil {
+			rl[corev1.ResourceName(name)] = q
+		}
+	}
+	return rl
+}
+
+// usageByName of the pods or nodes in a Query table, keyed by usageKey. Returns nil for other kinds, when the metrics
+// API isn't served or when it fails.
+func (kc *KubeCluster) usageByName(ctx context.Context, r metav1.APIResource, nsName string) map[string]corev1.ResourceList {
+	if kc.metrics == nil {
+		return nil
+	}
+
+	usage := make(map[string]corev1.ResourceList)
+	switch toGK(r) {
+	case podGK:
+		pods, err := kc.metrics.ListPodMetrics(ctx, nsName)
+		if err != nil {
+			log.Warnf("pod metrics unavailable: %v", err)
+			return nil
+		}
+		for _, pm := range pods {
+			total := make(corev1.ResourceList)
+			for _, c := range pm.Containers {
+				addResources(total, c.Usage)
+			}
+			usage[usageKey(pm.Namespace, pm.Name)] = total
+		}
+	case nodeGK:
+		nodes, err := kc.metrics.ListNodeMetrics(ctx)
+		if err != nil {
+			log.Warnf("node metrics unavailable: %v", err)
+			return nil
+		}
+		for _, nm := range nodes {
+			usage[usageKey("", nm.Name)] = nm.Usage
+		}
+	default:
+		return nil
+	}
+	return usage
+}
+
+// usageKey since pods in different namespaces may share a name. Nodes have no namespace.
+func usageKey(namespace string, name string) string {
+	return namespace + "/" + name
+}
+
+func addResources(total corev1.ResourceList, rl corev1.ResourceList) {
+	for name, q := range rl {
+		sum := total[name]
+		sum.Add(q)
+		total[name] = sum
+	}
+}
+
+// addUsageColumns appends CPU and Memory columns to a table whose rows are rowNamespaces and rowNames.
+func addUsageColumns(table *metav1.Table, rowNamespaces []string, rowNames []string, usage map[string]corev1.ResourceList) {
+	table.ColumnDefinitions = append(table.ColumnDefinitions,
+		metav1.TableColumnDefinition{Name: "CPU", Type: "string", Description: "Current CPU usage from the metrics API"},
+		metav1.TableColumnDefinition{Name: "Memory", Type: "string", Description: "Current memory usage from the metrics API"},
+	)
+	for i := range table.Rows {
+		cpu, memory := "<unknown>", "<unknown>"
+		if rl, found := usage[usageKey(rowNamespaces[i], rowNames[i])]; found {
+			if q, found := rl[corev1.ResourceCPU]; found {
+				cpu = q.String()
+			}
+			if q, found := rl[corev1.ResourceMemory]; found {
+				memory = q.String()
+			}
+		}
+		table.Rows[i].Cells = append(table.Rows[i].Cells, cpu, memory)
+	}
+}
+
+// findContainerUsage for a pod's object page. Nil when metrics aren't available, ie the pod just started.
+func (kc *KubeCluster) findContainerUsage(ctx context.Context, pod *corev1.Pod) []ContainerUsage {
+	if kc.metrics == nil {
+		return nil
+	}
+	pm, err := kc.metrics.GetPodMetrics(ctx, pod.Namespace, pod.Name)
+	if err != nil {
+		log.Warnf("pod metrics unavailable: %v", err)
+		return nil
+	}
+	return containerUsage(pod, pm)
+}
+
+func containerUsage(pod *corev1.Pod, pm *PodMetrics) []ContainerUsage {
+	byName := make(map[string]corev1.ResourceList, len(pm.Containers))
+	for _, c := range pm.Containers {
+		byName[c.Name] = c.Usage
+	}
+
+	usage := make([]ContainerUsage, 0, len(pod.Spec.Containers))
+	for _, c := range pod.Spec.Containers {
+		used, found := byName[c.Name]
+		if !found {
+			continue
+		}
+		usage = append(usage, ContainerUsage{
+			Name:   c.Name,
+			CPU:    resourceUsage(corev1.ResourceCPU, used, c.Resources, true),
+			Memory: resourceUsage(corev1.ResourceMemory, used, c.Resources, false),
+		})
+	}
+	return usage
+}
+
+func resourceUsage(name corev1.ResourceName, used corev1.ResourceList, requirements corev1.ResourceRequirements, milli bool) ResourceUsage {
+	ru := ResourceUsage{Usage: used[name], Request: requirements.Requests[name], Limit: requirements.Limits[name]}
+	value := func(q resource.Quantity) int64 {
+		if milli {
+			return q.MilliValue()
+		}
+		return q.Value()
+	}
+	if request := value(ru.Request); request != 0 {
+		ru.RequestPercent = value(ru.Usage) * 100 / request
+	}
+	if limit := value(ru.Limit); limit != 0 {
+		ru.LimitPercent = value(ru.Usage) * 100 / limit
+	}
+	return ru
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeMetrics serves fixed usage, or err for every call.
+type fakeMetrics struct {
+	pods  []PodMetrics
+	nodes []NodeMetrics
+	err   error
+}
+
+func (fm *fakeMetrics) ListPodMetrics(ctx context.Context, nsName string) ([]PodMetrics, error) {
+	return fm.pods, fm.err
+}
+
+func (fm *fakeMetrics) GetPodMetrics(ctx context.Context, nsName string, name string) (*PodMetrics, error) {
+	if fm.err != nil {
+		return nil, fm.err
+	}
+	for i := range fm.pods {
+		if fm.pods[i].Name == name {
+			return &fm.pods[i], nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (fm *fakeMetrics) ListNodeMetrics(ctx context.Context) ([]NodeMetrics, error) {
+	return fm.nodes, fm.err
+}
+
+func usageList(cpu string, memory string) corev1.ResourceList {
+	return corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu), corev1.ResourceMemory: resource.MustParse(memory)}
+}
+
+func TestQueryUsageColumns(t *testing.T) {
+	podResource := metav1.APIResource{Kind: "Pod", Name: "pods", Namespaced: true}
+	newTable := func() *metav1.Table {
+		return &metav1.Table{
+			ColumnDefinitions: []metav1.TableColumnDefinition{{Name: "Name", Type: "string"}},
+			Rows:              []metav1.TableRow{{Cells: []interface{}{"product"}}, {Cells: []interface{}{"cart"}}, {Cells: []interface{}{"product"}}},
+		}
+	}
+
+	// Pods of every namespace, two of them named product
+	kc := &KubeCluster{metrics: &fakeMetrics{pods: []PodMetrics{
+		{
+			Namespace: "back-end", Name: "product",
+			Containers: []ContainerMetrics{{Name: "app", Usage: usageList("100m", "64Mi")}, {Name: "proxy", Usage: usageList("5m", "16Mi")}},
+		},
+		{Namespace: "staging", Name: "product", Containers: []ContainerMetrics{{Name: "app", Usage: usageList("1m", "8Mi")}}},
+	}}}
+	table := newTable()
+	rowNamespaces := []string{"back-end", "back-end", "staging"}
+	rowNames := []string{"product", "cart", "product"}
+	addUsageColumns(table, rowNamespaces, rowNames, kc.usageByName(context.Background(), podResource, ""))
+
+	expected := [][]interface{}{{"product", "105m", "80Mi"}, {"cart", "<unknown>", "<unknown>"}, {"product", "1m", "8Mi"}}
+	for i, row := range table.Rows {
+		if !reflect.DeepEqual(expected[i], row.Cells) {
+			t.Errorf("expected %v, got %v", expected[i], row.Cells)
+		}
+	}
+
+	// No metrics API, or a failing one, leaves the table alone
+	for _, kc := range []*KubeCluster{{}, {metrics: &fakeMetrics{err: errors.New("service unavailable")}}} {
+		if usage := kc.usageByName(context.Background(), podResource, "back-end"); usage != nil {
+			t.Errorf("expected no usage, got %v", usage)
+		}
+	}
+}
+
+func TestContainerUsage(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "product", Namespace: "back-end"},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Resources: corev1.ResourceRequirements{Requests: usageList("200m", "128Mi"), Limits: usageList("1", "256Mi")}},
+			{Name: "proxy"},
+		}},
+	}
+	kc := &KubeCluster{metrics: &fakeMetrics{pods: []PodMetrics{{
+		Namespace: "back-end", Name: "product",
+		Containers: []ContainerMetrics{{Name: "app", Usage: usageList("100m", "192Mi")}, {Name: "proxy", Usage: usageList("5m", "16Mi")}},
+	}}}}
+
+	usage := kc.findContainerUsage(context.Background(), pod)
+	if len(usage) != 2 {
+		t.Fatalf("expected usage of 2 containers, got %+v", usage)
+	}
+	app := usage[0]
+	if app.CPU.RequestPercent != 50 || app.CPU.LimitPercent != 10 || app.Memory.RequestPercent != 150 || app.Memory.LimitPercent != 75 {
+		t.Errorf("unexpected usage of app %+v", app)
+	}
+	proxy := usage[1]
+	if proxy.CPU.Usage.String() != "5m" || proxy.CPU.RequestPercent != 0 || proxy.Memory.LimitPercent != 0 {
+		t.Errorf("expected usage without percents for proxy, got %+v", proxy)
+	}
+}
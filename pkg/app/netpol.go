@@ -0,0 +1,191 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cheriot/kubenav/pkg/app/relations"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	networkingv1client "k8s.io/client-go/kubernetes/typed/networking/v1"
+)
+
+var networkPolicyGK = schema.GroupKind{Group: networkingv1.GroupName, Kind: "NetworkPolicy"}
+
+// NetworkAccess summarizes what every NetworkPolicy selecting a pod allows.
+type NetworkAccess struct {
+	Namespace string                        `json:"namespace"`
+	Pod       string                        `json:"pod"`
+	Policies  []relations.HasOneDestination `json:"policies"`
+	Ingress   DirectionAccess               `json:"ingress"`
+	Egress    DirectionAccess               `json:"egress"`
+}
+
+// DirectionAccess is the traffic allowed in one direction. Policies are additive so traffic is allowed when any rule
+// allows it.
+type DirectionAccess struct {
+	// A policy of this direction selects the pod. Otherwise all traffic is allowed.
+	Isolated bool `json:"isolated"`
+	// Isolated without any rules. ie a default deny policy
+	DenyAll bool         `json:"denyAll"`
+	Rules   []AccessRule `json:"rules"`
+}
+
+// AccessRule is one ingress or egress rule. Traffic from/to any of the peers on any of the ports is allowed.
+type AccessRule struct {
+	Policy string   `json:"policy"`
+	Peers  []string `json:"peers"`
+	Ports  []string `json:"ports"`
+}
+
+// NetworkAccess finds the NetworkPolicies that select a pod and what traffic they allow to and from it.
+func (kc *KubeCluster) NetworkAccess(ctx context.Context, nsName string, podName string) (*NetworkAccess, error) {
+	coreclient, err := corev1client.NewForConfig(kc.restClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create coreclient for %s: %w", kc.name, err)
+	}
+	networkingclient, err := networkingv1client.NewForConfig(kc.restClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create networkingclient for %s: %w", kc.name, err)
+	}
+
+	pod, err := coreclient.Pods(nsName).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get pod %s/%s: %w", nsName, podName, err)
+	}
+	policies, err := networkingclient.NetworkPolicies(nsName).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list networkpolicies in %s: %w", nsName, err)
+	}
+
+	access := summarizeNetworkAccess(pod, policies.Items)
+	return &access, nil
+}
+
+func summarizeNetworkAccess(pod *corev1.Pod, policies []networkingv1.NetworkPolicy) NetworkAccess {
+	access := NetworkAccess{
+		Namespace: pod.Namespace,
+		Pod:       pod.Name,
+		Policies:  make([]relations.HasOneDestination, 0),
+		Ingress:   DirectionAccess{Rules: make([]AccessRule, 0)},
+		Egress:    DirectionAccess{Rules: make([]AccessRule, 0)},
+	}
+
+	for i := range policies {
+		np := &policies[i]
+		if !relations.NetworkPolicySelectsPod(np, pod) {
+			continue
+		}
+		access.Policies = append(access.Policies, relations.HasOneDestination{
+			GroupKind: networkPolicyGK,
+			Namespace: np.Namespace,
+			Name:      np.Name,
+		})
+
+		ingress, egress := policyTypes(np)
+		if ingress {
+			access.Ingress.Isolated = true
+			for _, rule := range np.Spec.Ingress {
+				access.Ingress.Rules = append(access.Ingress.Rules, AccessRule{
+					Policy: np.Name,
+					Peers:  peerDescriptions(rule.From),
+					Ports:  portDescriptions(rule.Ports),
+				})
+			}
+		}
+		if egress {
+			access.Egress.Isolated = true
+			for _, rule := range np.Spec.Egress {
+				access.Egress.Rules = append(access.Egress.Rules, AccessRule{
+					Policy: np.Name,
+					Peers:  peerDescriptions(rule.To),
+					Ports:  portDescriptions(rule.Ports),
+				})
+			}
+		}
+	}
+
+	access.Ingress.DenyAll = access.Ingress.Isolated && len(access.Ingress.Rules) == 0
+	access.Egress.DenyAll = access.Egress.Isolated && len(access.Egress.Rules) == 0
+	return access
+}
+
+// policyTypes follows the NetworkPolicySpec default: Ingress always, Egress only when there are egress rules.
+func policyTypes(np *networkingv1.NetworkPolicy) (ingress bool, egress bool) {
+	if len(np.Spec.PolicyTypes) == 0 {
+		return true, len(np.Spec.Egress) > 0
+	}
+	for _, pt := range np.Spec.PolicyTypes {
+		switch pt {
+		case networkingv1.PolicyTypeIngress:
+			ingress = true
+		case networkingv1.PolicyTypeEgress:
+			egress = true
+		}
+	}
+	return ingress, egress
+}
+
+func peerDescriptions(peers []networkingv1.NetworkPolicyPeer) []string {
+	if len(peers) == 0 {
+		return []string{"any"}
+	}
+
+	descriptions := make([]string, 0, len(peers))
+	for _, peer := range peers {
+		switch {
+		case peer.IPBlock != nil:
+			d := peer.IPBlock.CIDR
+			if len(peer.IPBlock.Except) > 0 {
+				d = fmt.Sprintf("%s except %s", d, strings.Join(peer.IPBlock.Except, ", "))
+			}
+			descriptions = append(descriptions, d)
+		case peer.NamespaceSelector != nil && peer.PodSelector != nil:
+			descriptions = append(descriptions, fmt.Sprintf("pods %s in namespaces %s", selectorDescription(peer.PodSelector), selectorDescription(peer.NamespaceSelector)))
+		case peer.NamespaceSelector != nil:
+			descriptions = append(descriptions, fmt.Sprintf("namespaces %s", selectorDescription(peer.NamespaceSelector)))
+		case peer.PodSelector != nil:
+			descriptions = append(descriptions, fmt.Sprintf("pods %s", selectorDescription(peer.PodSelector)))
+		}
+	}
+	return descriptions
+}
+
+func selectorDescription(ls *metav1.LabelSelector) string {
+	selector, err := metav1.LabelSelectorAsSelector(ls)
+	if err != nil {
+		return fmt.Sprintf("invalid selector: %s", err.Error())
+	}
+	if selector.Empty() {
+		return "all"
+	}
+	return selector.String()
+}
+
+func portDescriptions(ports []networkingv1.NetworkPolicyPort) []string {
+	if len(ports) == 0 {
+		return []string{"any"}
+	}
+
+	descriptions := make([]string, 0, len(ports))
+	for _, p := range ports {
+		protocol := corev1.ProtocolTCP
+		if p.Protocol != nil {
+			protocol = *p.Protocol
+		}
+		switch {
+		case p.Port == nil:
+			descriptions = append(descriptions, fmt.Sprintf("%s/any", protocol))
+		case p.EndPort != nil:
+			descriptions = append(descriptions, fmt.Sprintf("%s/%s-%d", protocol, p.Port.String(), *p.EndPort))
+		default:
+			descriptions = append(descriptions, fmt.Sprintf("%s/%s", protocol, p.Port.String()))
+		}
+	}
+	return descriptions
+}
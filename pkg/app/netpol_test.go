@@ -0,0 +1,62 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestSummarizeNetworkAccess(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "product-0", Namespace: "back-end", Labels: map[string]string{"app": "product"}}}
+
+	defaultDeny := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default-deny", Namespace: "back-end"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}
+	port := intstr.FromInt(8080)
+	allowFrontEnd := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-front-end", Namespace: "back-end"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "product"}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"name": "front-end"}},
+				}},
+				Ports: []networkingv1.NetworkPolicyPort{{Port: &port}},
+			}},
+		},
+	}
+	otherPods := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "cart", Namespace: "back-end"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "cart"}},
+		},
+	}
+
+	actual := summarizeNetworkAccess(pod, []networkingv1.NetworkPolicy{defaultDeny, allowFrontEnd, otherPods})
+
+	if len(actual.Policies) != 2 {
+		t.Errorf("expected the default-deny and allow-front-end policies, got %+v", actual.Policies)
+	}
+	expectedIngress := DirectionAccess{
+		Isolated: true,
+		Rules: []AccessRule{{
+			Policy: "allow-front-end",
+			Peers:  []string{"namespaces name=front-end"},
+			Ports:  []string{"TCP/8080"},
+		}},
+	}
+	if !reflect.DeepEqual(actual.Ingress, expectedIngress) {
+		t.Errorf("expected %+v, got %+v", expectedIngress, actual.Ingress)
+	}
+	expectedEgress := DirectionAccess{Isolated: true, DenyAll: true, Rules: []AccessRule{}}
+	if !reflect.DeepEqual(actual.Egress, expectedEgress) {
+		t.Errorf("expected %+v, got %+v", expectedEgress, actual.Egress)
+	}
+}
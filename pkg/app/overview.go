@@ -0,0 +1,295 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	util "github.com/cheriot/kubenav/internal/util"
+	"github.com/cheriot/kubenav/pkg/app/relations"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	overviewWarningLimit = 20
+	overviewRestartLimit = 10
+)
+
+var resourceQuotaGK = schema.GroupKind{Kind: "ResourceQuota"}
+
+// NamespaceOverview is the landing page of a namespace.
+type NamespaceOverview struct {
+	Namespace string      `json:"namespace"`
+	Counts    []KindCount `json:"counts"`
+	// Objects that are Progressing or Degraded, worst first
+	Unhealthy []UnhealthyObject `json:"unhealthy"`
+	// Most recent first
+	Warnings []WarningEvent `json:"warnings"`
+	// Pods with the most container restarts
+	Restarts []PodRestarts `json:"restarts"`
+	// Printed the same as a query for resourcequotas
+	Quotas *ResourceTable `json:"quotas"`
+	// ie a kind that couldn't be listed
+	Errors []string `json:"errors"`
+}
+
+type KindCount struct {
+	APIResource metav1.APIResource `json:"apiResource"`
+	Count       int                `json:"count"`
+	// There are more than Count, but the api server didn't say how many
+	Truncated bool `json:"truncated"`
+}
+
+type UnhealthyObject struct {
+	Object relations.HasOneDestination `json:"object"`
+	Health Health                      `json:"health"`
+}
+
+type WarningEvent struct {
+	Object   relations.HasOneDestination `json:"object"`
+	Reason   string                      `json:"reason"`
+	Message  string                      `json:"message"`
+	Count    int32                       `json:"count"`
+	LastSeen metav1.Time                 `json:"lastSeen"`
+}
+
+type PodRestarts struct {
+	Name     string `json:"name"`
+	Restarts int32  `json:"restarts"`
+	// Why the container that restarted most last terminated. ie OOMKilled
+	LastReason string `json:"lastReason"`
+}
+
+// NamespaceOverview lists every kind in the namespace, QUERY_WORKERS at a time.
+func (kc *KubeCluster) NamespaceOverview(ctx context.Context, nsName string) (*NamespaceOverview, error) {
+	overview := &NamespaceOverview{
+		Namespace: nsName,
+		Counts:    make([]KindCount, 0),
+		Unhealthy: make([]UnhealthyObject, 0),
+		Warnings:  make([]WarningEvent, 0),
+		Restarts:  make([]PodRestarts, 0),
+		Errors:    make([]string, 0),
+	}
+
+	var warnings []WarningEvent
+	var warningsErr error
+	warningsDone := make(chan struct{})
+	go func() {
+		defer close(warningsDone)
+		warnings, warningsErr = kc.warningEvents(ctx, nsName)
+	}()
+
+	// Dozens of kinds. List them concurrently, but not all at once.
+	resources := overviewResources(kc.apiResources)
+	listings := util.MapConcurrent(resources, QUERY_WORKERS, func(r metav1.APIResource) overviewListing {
+		return kc.overviewList(ctx, r, nsName)
+	})
+	<-warningsDone
+
+	items := make(map[schema.GroupKind][]unstructured.Unstructured)
+	for i, listing := range listings {
+		if listing.err != nil {
+			overview.Errors = append(overview.Errors, listing.err.Error())
+			continue
+		}
+		if listing.items != nil {
+			items[toGK(resources[i])] = listing.items
+		}
+		overview.Counts = append(overview.Counts, listing.count)
+	}
+	if warningsErr != nil {
+		overview.Errors = append(overview.Errors, warningsErr.Error())
+	} else {
+		overview.Warnings = warnings
+	}
+
+	sort.Slice(overview.Counts, func(i, j int) bool {
+		if overview.Counts[i].Count != overview.Counts[j].Count {
+			return overview.Counts[i].Count > overview.Counts[j].Count
+		}
+		return overview.Counts[i].APIResource.Kind < overview.Counts[j].APIResource.Kind
+	})
+	sort.Strings(overview.Errors)
+	overview.Unhealthy = unhealthyObjects(items)
+	overview.Restarts = topRestarts(items[podGK], overviewRestartLimit)
+
+	if r, found := findAPIResourceByGK(kc.apiResources, resourceQuotaGK); found {
+		quotas := ResourceTable{APIResource: r}
+		if quotaItems, listed := items[resourceQuotaGK]; listed && len(quotaItems) > 0 {
+			uList := &unstructured.UnstructuredList{Items: quotaItems}
+			uList.SetGroupVersionKind(toGV(r).WithKind(r.Kind + "List"))
+			table, err := PrintList(kc.scheme, r, uList)
+			if err != nil {
+				overview.Errors = append(overview.Errors, fmt.Sprintf("unable to print resource quotas: %s", err.Error()))
+				table = PrintError(err)
+			}
+			quotas.Table = table
+			quotas.IsError = err != nil
+			quotas.TableRowNames = util.Map(quotaItems, func(u unstructured.Unstructured) string { return u.GetName() })
+			overview.Quotas = &quotas
+		}
+	}
+
+	return overview, nil
+}
+
+// overviewResources are the namespaced kinds that can be listed, except events which are summarized separately.
+func overviewResources(apiResources []metav1.APIResource) []metav1.APIResource {
+	return util.Filter(apiResources, func(r metav1.APIResource) bool {
+		return r.Namespaced && util.Contains(r.Verbs, "list") && r.Kind != "Event"
+	})
+}
+
+// overviewListing of one kind. items are only listed for the kinds that are summarized.
+type overviewListing struct {
+	count KindCount
+	items []unstructured.Unstructured
+	err   error
+}
+
+// overviewList lists the whole object of the kinds with a health check, pods, and quotas. The rest are only counted,
+// so their metadata is enough. No reason to download every Secret's data.
+func (kc *KubeCluster) overviewList(ctx context.Context, r metav1.APIResource, nsName string) overviewListing {
+	gk := toGK(r)
+	if _, found := healthChecks[gk]; found || gk == podGK || gk == resourceQuotaGK {
+		uList, err := kc.listUnstructured(ctx, r, nsName, metav1.ListOptions{})
+		if err != nil {
+			return overviewListing{err: err}
+		}
+		return overviewListing{
+			count: kindCount(r, len(uList.Items), uList.GetRemainingItemCount(), uList.GetContinue()),
+			items: uList.Items,
+		}
+	}
+
+	mList, err := kc.listMetadata(ctx, r, nsName, metav1.ListOptions{})
+	if err != nil {
+		return overviewListing{err: err}
+	}
+	return overviewListing{count: kindCount(r, len(mList.Items), mList.RemainingItemCount, mList.Continue)}
+}
+
+// kindCount of a list that may have stopped at LIST_LIMIT. The api server usually says how many are left.
+func kindCount(r metav1.APIResource, listed int, remaining *int64, continueToken string) KindCount {
+	count := KindCount{APIResource: r, Count: listed}
+	if remaining != nil {
+		count.Count += int(*remaining)
+	} else {
+		count.Truncated = continueToken != ""
+	}
+	return count
+}
+
+func (kc *KubeCluster) warningEvents(ctx context.Context, nsName string) ([]WarningEvent, error) {
+	coreclient, err := corev1client.NewForConfig(kc.restClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create coreclient for %s: %w", kc.name, err)
+	}
+
+	events, err := coreclient.Events(nsName).List(ctx, metav1.ListOptions{FieldSelector: "type=" + corev1.EventTypeWarning})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list warning events in %s: %w", nsName, err)
+	}
+	return recentWarnings(events.Items, overviewWarningLimit), nil
+}
+
+func recentWarnings(events []corev1.Event, limit int) []WarningEvent {
+	warnings := util.Map(events, func(e corev1.Event) WarningEvent {
+		lastSeen := e.LastTimestamp
+		if lastSeen.IsZero() {
+			lastSeen = metav1.NewTime(e.EventTime.Time)
+		}
+		count := e.Count
+		if e.Series != nil {
+			count = e.Series.Count
+		}
+		return WarningEvent{
+			Object: relations.HasOneDestination{
+				GroupKind: schema.FromAPIVersionAndKind(e.InvolvedObject.APIVersion, e.InvolvedObject.Kind).GroupKind(),
+				Namespace: e.InvolvedObject.Namespace,
+				Name:      e.InvolvedObject.Name,
+			},
+			Reason:   e.Reason,
+			Message:  e.Message,
+			Count:    count,
+			LastSeen: lastSeen,
+		}
+	})
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return warnings[j].LastSeen.Before(&warnings[i].LastSeen)
+	})
+	if len(warnings) > limit {
+		warnings = warnings[:limit]
+	}
+	return warnings
+}
+
+// unhealthyObjects of the kinds with a HealthCheck. Custom resources aren't included since most have no conditions.
+func unhealthyObjects(items map[schema.GroupKind][]unstructured.Unstructured) []UnhealthyObject {
+	unhealthy := make([]UnhealthyObject, 0)
+	for gk, objs := range items {
+		if _, found := healthChecks[gk]; !found {
+			continue
+		}
+		for i := range objs {
+			health := ObjectHealth(&objs[i], gk)
+			if health.Status == Progressing || health.Status == Degraded {
+				unhealthy = append(unhealthy, UnhealthyObject{
+					Object: relations.HasOneDestination{GroupKind: gk, Namespace: objs[i].GetNamespace(), Name: objs[i].GetName()},
+					Health: health,
+				})
+			}
+		}
+	}
+	sort.Slice(unhealthy, func(i, j int) bool {
+		si, sj := healthSeverity[unhealthy[i].Health.Status], healthSeverity[unhealthy[j].Health.Status]
+		if si != sj {
+			return si > sj
+		}
+		return relations.NodeID(unhealthy[i].Object) < relations.NodeID(unhealthy[j].Object)
+	})
+	return unhealthy
+}
+
+func topRestarts(pods []unstructured.Unstructured, limit int) []PodRestarts {
+	restarts := make([]PodRestarts, 0)
+	for i := range pods {
+		typed, err := relations.Typed(&pods[i])
+		if err != nil {
+			continue
+		}
+		pod, ok := typed.(*corev1.Pod)
+		if !ok {
+			continue
+		}
+
+		pr := PodRestarts{Name: pod.Name}
+		var most int32 = -1
+		for _, cs := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			pr.Restarts += cs.RestartCount
+			if cs.RestartCount > most && cs.LastTerminationState.Terminated != nil {
+				most = cs.RestartCount
+				pr.LastReason = cs.LastTerminationState.Terminated.Reason
+			}
+		}
+		if pr.Restarts > 0 {
+			restarts = append(restarts, pr)
+		}
+	}
+
+	sort.SliceStable(restarts, func(i, j int) bool {
+		if restarts[i].Restarts != restarts[j].Restarts {
+			return restarts[i].Restarts > restarts[j].Restarts
+		}
+		return restarts[i].Name < restarts[j].Name
+	})
+	if len(restarts) > limit {
+		restarts = restarts[:limit]
+	}
+	return restarts
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cheriot/kubenav/pkg/app/relations"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestNamespaceOverviewSummaries(t *testing.T) {
+	toUnstructured := func(pod *corev1.Pod) unstructured.Unstructured {
+		pod.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return unstructured.Unstructured{Object: content}
+	}
+	oomKilled := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled"}}
+	pods := []unstructured.Unstructured{
+		toUnstructured(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "cart", Namespace: "back-end"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+		}),
+		toUnstructured(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "product", Namespace: "back-end"},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning, ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "app", RestartCount: 7, LastTerminationState: oomKilled, State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}},
+				{Name: "proxy", RestartCount: 1, Ready: true},
+			}},
+		}),
+	}
+
+	expectedRestarts := []PodRestarts{{Name: "product", Restarts: 8, LastReason: "OOMKilled"}}
+	if actual := topRestarts(pods, 10); !reflect.DeepEqual(expectedRestarts, actual) {
+		t.Errorf("expected %+v, got %+v", expectedRestarts, actual)
+	}
+
+	expectedUnhealthy := []UnhealthyObject{{
+		Object: relations.HasOneDestination{GroupKind: podGK, Namespace: "back-end", Name: "product"},
+		Health: Health{Status: Degraded, Reasons: []string{"container app: CrashLoopBackOff, 7 restarts"}},
+	}}
+	items := map[schema.GroupKind][]unstructured.Unstructured{podGK: pods}
+	if actual := unhealthyObjects(items); !reflect.DeepEqual(expectedUnhealthy, actual) {
+		t.Errorf("expected %+v, got %+v", expectedUnhealthy, actual)
+	}
+
+	now := time.Now()
+	events := []corev1.Event{
+		{InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "old"}, Reason: "BackOff", LastTimestamp: metav1.NewTime(now.Add(-time.Hour))},
+		{InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "new"}, Reason: "FailedMount", LastTimestamp: metav1.NewTime(now)},
+		{InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "middle"}, Reason: "Unhealthy", EventTime: metav1.NewMicroTime(now.Add(-time.Minute))},
+	}
+	warnings := recentWarnings(events, 2)
+	actualNames := []string{warnings[0].Object.Name, warnings[1].Object.Name}
+	if !reflect.DeepEqual([]string{"new", "middle"}, actualNames) {
+		t.Errorf("expected the most recent warnings first, got %v", actualNames)
+	}
+}
+
+func TestOverviewList(t *testing.T) {
+	kc, dynamicClient := fakeCluster(t, deploymentObjects()...)
+	scheme := metadatafake.NewTestScheme()
+	metav1.AddMetaToScheme(scheme)
+	configMap := func(name string) runtime.Object {
+		return &metav1.PartialObjectMetadata{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}, ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "back-end"}}
+	}
+	metadataClient := metadatafake.NewSimpleMetadataClient(scheme, configMap("product"), configMap("cart"))
+	kc.metadataClient = metadataClient
+	// More secrets than LIST_LIMIT and no remainingItemCount, like when the api server can't count them
+	metadataClient.PrependReactor("list", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		secret := &metav1.PartialObjectMetadata{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"}, ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "back-end"}}
+		return true, &metav1.List{ListMeta: metav1.ListMeta{Continue: "next"}, Items: []runtime.RawExtension{{Object: secret}}}, nil
+	})
+	resource := func(kind string) metav1.APIResource {
+		r, _ := findAPIResourceByGK(kc.apiResources, schema.GroupKind{Kind: kind})
+		return r
+	}
+
+	// Pods are summarized, so they're listed whole
+	pods := kc.overviewList(context.Background(), resource("Pod"), "back-end")
+	if pods.err != nil || pods.count.Count != 3 || pods.count.Truncated || len(pods.items) != 3 {
+		t.Errorf("expected three pods, got %+v", pods)
+	}
+
+	configMaps := kc.overviewList(context.Background(), resource("ConfigMap"), "back-end")
+	if configMaps.err != nil || configMaps.count.Count != 2 || configMaps.count.Truncated || configMaps.items != nil {
+		t.Errorf("expected two config maps counted, got %+v", configMaps)
+	}
+	for _, action := range dynamicClient.Actions() {
+		if action.GetResource().Resource != "pods" {
+			t.Errorf("expected only pods listed whole, got %v", action)
+		}
+	}
+
+	secrets := kc.overviewList(context.Background(), resource("Secret"), "back-end")
+	if secrets.err != nil || secrets.count.Count != 1 || !secrets.count.Truncated {
+		t.Errorf("expected a truncated count, got %+v", secrets)
+	}
+
+	remaining := int64(1500)
+	if count := kindCount(resource("Secret"), 1000, &remaining, "next"); count.Count != 2500 || count.Truncated {
+		t.Errorf("expected the remaining secrets counted, got %+v", count)
+	}
+}
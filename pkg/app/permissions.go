@@ -0,0 +1,162 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cheriot/kubenav/pkg/app/relations"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	rbacv1client "k8s.io/client-go/kubernetes/typed/rbac/v1"
+)
+
+// Permissions are every rule granted to a subject by the cluster's bindings.
+type Permissions struct {
+	Subject rbacv1.Subject `json:"subject"`
+	Grants  []Grant        `json:"grants"`
+	// ie a binding to a role that doesn't exist
+	Errors []string `json:"errors"`
+}
+
+// Grant is the rules of one role, granted by one binding.
+type Grant struct {
+	// Empty when the rules apply to every namespace
+	Namespace string                      `json:"namespace"`
+	Binding   relations.HasOneDestination `json:"binding"`
+	Role      relations.HasOneDestination `json:"role"`
+	// The subject of the binding. Differs from Permissions.Subject when granted to one of its groups.
+	BoundTo rbacv1.Subject      `json:"boundTo"`
+	Rules   []rbacv1.PolicyRule `json:"rules"`
+}
+
+var (
+	roleBindingGK        = schema.GroupKind{Group: rbacv1.GroupName, Kind: "RoleBinding"}
+	clusterRoleBindingGK = schema.GroupKind{Group: rbacv1.GroupName, Kind: "ClusterRoleBinding"}
+	roleGK               = schema.GroupKind{Group: rbacv1.GroupName, Kind: "Role"}
+	clusterRoleGK        = schema.GroupKind{Group: rbacv1.GroupName, Kind: "ClusterRole"}
+)
+
+// Permissions answers "what can this subject do" by aggregating the roles of every ClusterRoleBinding and
+// RoleBinding that includes it. subject.Kind is ServiceAccount, User or Group. A ServiceAccount's namespace is
+// required.
+func (kc *KubeCluster) Permissions(ctx context.Context, subject rbacv1.Subject) (*Permissions, error) {
+	if subject.Kind == rbacv1.ServiceAccountKind && subject.Namespace == "" {
+		return nil, fmt.Errorf("unable to find permissions of serviceaccount %s without a namespace", subject.Name)
+	}
+
+	rbacclient, err := rbacv1client.NewForConfig(kc.restClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create rbacclient for %s: %w", kc.name, err)
+	}
+
+	clusterRoleBindings, err := rbacclient.ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list clusterrolebindings: %w", err)
+	}
+	roleBindings, err := rbacclient.RoleBindings("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list rolebindings: %w", err)
+	}
+
+	permissions := &Permissions{
+		Subject: subject,
+		Grants:  make([]Grant, 0),
+		Errors:  make([]string, 0),
+	}
+
+	// Bindings commonly share a ClusterRole. ie view, edit, admin
+	clusterRoles := make(map[string]*rbacv1.ClusterRole)
+	roleRules := func(ns string, roleRef rbacv1.RoleRef) ([]rbacv1.PolicyRule, error) {
+		if roleRef.Kind == clusterRoleGK.Kind {
+			if cr, found := clusterRoles[roleRef.Name]; found {
+				return cr.Rules, nil
+			}
+			cr, err := rbacclient.ClusterRoles().Get(ctx, roleRef.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			clusterRoles[roleRef.Name] = cr
+			return cr.Rules, nil
+		}
+		role, err := rbacclient.Roles(ns).Get(ctx, roleRef.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return role.Rules, nil
+	}
+
+	addGrant := func(binding relations.HasOneDestination, roleRef rbacv1.RoleRef, subjects []rbacv1.Subject) {
+		boundTo, found := findBoundSubject(subject, binding.Namespace, subjects)
+		if !found {
+			return
+		}
+
+		role := relations.HasOneDestination{GroupKind: clusterRoleGK, Name: roleRef.Name}
+		if roleRef.Kind == roleGK.Kind {
+			role = relations.HasOneDestination{GroupKind: roleGK, Namespace: binding.Namespace, Name: roleRef.Name}
+		}
+
+		rules, err := roleRules(binding.Namespace, roleRef)
+		if err != nil {
+			permissions.Errors = append(permissions.Errors, fmt.Sprintf("unable to get %s %s of %s %s: %s", roleRef.Kind, roleRef.Name, binding.Kind, binding.Name, err.Error()))
+			return
+		}
+
+		permissions.Grants = append(permissions.Grants, Grant{
+			Namespace: binding.Namespace,
+			Binding:   binding,
+			Role:      role,
+			BoundTo:   boundTo,
+			Rules:     rules,
+		})
+	}
+
+	for _, crb := range clusterRoleBindings.Items {
+		addGrant(relations.HasOneDestination{GroupKind: clusterRoleBindingGK, Name: crb.Name}, crb.RoleRef, crb.Subjects)
+	}
+	for _, rb := range roleBindings.Items {
+		addGrant(relations.HasOneDestination{GroupKind: roleBindingGK, Namespace: rb.Namespace, Name: rb.Name}, rb.RoleRef, rb.Subjects)
+	}
+
+	return permissions, nil
+}
+
+// findBoundSubject finds the binding's subject that is, or is a group containing, subject.
+func findBoundSubject(subject rbacv1.Subject, bindingNamespace string, subjects []rbacv1.Subject) (rbacv1.Subject, bool) {
+	groups := implicitGroups(subject)
+	for _, s := range subjects {
+		switch {
+		case s.Kind == subject.Kind && s.Name == subject.Name &&
+			relations.SubjectNamespace(s, bindingNamespace) == relations.SubjectNamespace(subject, ""):
+			return s, true
+		// A ServiceAccount authenticates as this user, so bindings can name it either way
+		case subject.Kind == rbacv1.ServiceAccountKind && s.Kind == rbacv1.UserKind &&
+			s.Name == fmt.Sprintf("system:serviceaccount:%s:%s", subject.Namespace, subject.Name):
+			return s, true
+		case s.Kind == rbacv1.GroupKind:
+			for _, g := range groups {
+				if s.Name == g {
+					return s, true
+				}
+			}
+		}
+	}
+	return rbacv1.Subject{}, false
+}
+
+// implicitGroups the apiserver adds to every authenticated request from subject.
+func implicitGroups(subject rbacv1.Subject) []string {
+	switch subject.Kind {
+	case rbacv1.ServiceAccountKind:
+		return []string{
+			"system:serviceaccounts",
+			"system:serviceaccounts:" + subject.Namespace,
+			"system:authenticated",
+		}
+	case rbacv1.UserKind:
+		return []string{"system:authenticated"}
+	}
+	return []string{}
+}
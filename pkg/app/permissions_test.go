@@ -0,0 +1,34 @@
+package app
+
+import (
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestFindBoundSubject(t *testing.T) {
+	sa := rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: "back-end", Name: "product"}
+
+	tests := []struct {
+		name             string
+		bindingNamespace string
+		subjects         []rbacv1.Subject
+		found            bool
+	}{
+		{"direct", "", []rbacv1.Subject{sa}, true},
+		{"namespace defaults to the binding's", "back-end", []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "product"}}, true},
+		{"same name in another namespace", "", []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: "front-end", Name: "product"}}, false},
+		{"user with the same name", "", []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "product"}}, false},
+		{"serviceaccount's user name", "", []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "system:serviceaccount:back-end:product"}}, true},
+		{"another namespace's serviceaccount user name", "", []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "system:serviceaccount:front-end:product"}}, false},
+		{"namespace's serviceaccounts group", "", []rbacv1.Subject{{Kind: rbacv1.GroupKind, Name: "system:serviceaccounts:back-end"}}, true},
+		{"another namespace's serviceaccounts group", "", []rbacv1.Subject{{Kind: rbacv1.GroupKind, Name: "system:serviceaccounts:front-end"}}, false},
+	}
+
+	for _, tt := range tests {
+		_, found := findBoundSubject(sa, tt.bindingNamespace, tt.subjects)
+		if found != tt.found {
+			t.Errorf("%s: expected found %v, got %v", tt.name, tt.found, found)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+type PortForwardRequest struct {
+	Namespace string `json:"namespace"`
+	// Anything findAPIResources resolves to Pod or Service. ie po, pod, svc, service
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// 0 picks a free local port
+	LocalPort uint16 `json:"localPort"`
+	// A container port for pods or a service port for services
+	RemotePort uint16 `json:"remotePort"`
+}
+
+type PortForward struct {
+	ID        string    `json:"id"`
+	Namespace string    `json:"namespace"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Pod       string    `json:"pod"`
+	LocalPort uint16    `json:"localPort"`
+	PodPort   uint16    `json:"podPort"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// portForwardRegistry tracks the forwards a KubeCluster has running so they can be listed and stopped.
+type portForwardRegistry struct {
+	lock     sync.Mutex
+	nextID   int
+	forwards map[string]*activePortForward
+}
+
+type activePortForward struct {
+	PortForward
+	stopChan chan struct{}
+}
+
+func newPortForwardRegistry() *portForwardRegistry {
+	return &portForwardRegistry{forwards: make(map[string]*activePortForward)}
+}
+
+// Longest wait for the forward to listen locally
+const portForwardReadyTimeout = 30 * time.Second
+
+// StartPortForward forwards a local port to a pod, or to a ready pod backing a service, until
+// StopPortForward or StopAllPortForwards. ctx only bounds finding the pod and waiting for the forward to be ready.
+func (kc *KubeCluster) StartPortForward(ctx context.Context, req PortForwardRequest) (*PortForward, error) {
+	coreclient, err := corev1client.NewForConfig(kc.restClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create coreclient for %s: %w", kc.name, err)
+	}
+
+	matches := findAPIResources(kc.apiResources, req.Kind)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("unable to find an api resource: %s", req.Kind)
+	}
+
+	var pod *corev1.Pod
+	var podPort uint16
+	switch toGK(matches[0]) {
+	case schema.GroupKind{Kind: "Pod"}:
+		pod, err = coreclient.Pods(req.Namespace).Get(ctx, req.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("unable to get pod %s/%s: %w", req.Namespace, req.Name, err)
+		}
+		podPort = req.RemotePort
+	case schema.GroupKind{Kind: "Service"}:
+		pod, podPort, err = resolveServicePort(ctx, coreclient, req.Namespace, req.Name, req.RemotePort)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("port-forward supports pods and services, not %s", matches[0].Kind)
+	}
+
+	if pod.Status.Phase != corev1.PodRunning {
+		return nil, fmt.Errorf("unable to forward to pod %s/%s in phase %s", pod.Namespace, pod.Name, pod.Status.Phase)
+	}
+
+	url := coreclient.RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("portforward").
+		URL()
+	transport, upgrader, err := spdy.RoundTripperFor(kc.restClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create round tripper: %w", err)
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", url)
+
+	stopChan := make(chan struct{})
+	readyChan := make(chan struct{})
+	ports := []string{fmt.Sprintf("%d:%d", req.LocalPort, podPort)}
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{"localhost"}, ports, stopChan, readyChan, io.Discard, io.Discard)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create port forwarder: %w", err)
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- forwarder.ForwardPorts()
+	}()
+
+	select {
+	case err = <-errChan:
+		return nil, fmt.Errorf("port-forward to %s/%s failed: %w", pod.Namespace, pod.Name, err)
+	case <-ctx.Done():
+		close(stopChan)
+		return nil, fmt.Errorf("port-forward to %s/%s canceled: %w", pod.Namespace, pod.Name, ctx.Err())
+	case <-time.After(portForwardReadyTimeout):
+		close(stopChan)
+		return nil, fmt.Errorf("port-forward to %s/%s wasn't ready after %v", pod.Namespace, pod.Name, portForwardReadyTimeout)
+	case <-readyChan:
+	}
+
+	forwardedPorts, err := forwarder.GetPorts()
+	if err != nil || len(forwardedPorts) == 0 {
+		close(stopChan)
+		return nil, fmt.Errorf("unable to get forwarded ports: %w", err)
+	}
+
+	pf := kc.portForwards.add(PortForward{
+		Namespace: req.Namespace,
+		Kind:      matches[0].Kind,
+		Name:      req.Name,
+		Pod:       pod.Name,
+		LocalPort: forwardedPorts[0].Local,
+		PodPort:   podPort,
+		StartedAt: time.Now(),
+	}, stopChan)
+
+	// Forget the forward if it ends without being stopped. ie the pod was deleted.
+	go func() {
+		err := <-errChan
+		if err != nil {
+			log.Warnf("port-forward %s to %s/%s ended: %v", pf.ID, pf.Namespace, pf.Pod, err)
+		}
+		kc.portForwards.remove(pf.ID)
+	}()
+
+	return &pf, nil
+}
+
+func (kc *KubeCluster) ListPortForwards() []PortForward {
+	return kc.portForwards.list()
+}
+
+func (kc *KubeCluster) StopPortForward(id string) error {
+	if !kc.portForwards.stop(id) {
+		return fmt.Errorf("no port-forward with id %s", id)
+	}
+	return nil
+}
+
+func (kc *KubeCluster) StopAllPortForwards() {
+	for _, pf := range kc.portForwards.list() {
+		kc.portForwards.stop(pf.ID)
+	}
+}
+
+// resolveServicePort finds a ready pod selected by the service and the pod port its servicePort targets. A named
+// targetPort may only exist in some of the pods, ie during a rollout, so pods without it are skipped.
+func resolveServicePort(ctx context.Context, coreclient corev1client.CoreV1Interface, ns string, name string, servicePort uint16) (*corev1.Pod, uint16, error) {
+	svc, err := coreclient.Services(ns).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to get service %s/%s: %w", ns, name, err)
+	}
+	if len(svc.Spec.Selector) == 0 {
+		return nil, 0, fmt.Errorf("service %s/%s has no selector to find pods with", ns, name)
+	}
+
+	var targetPort *intstr.IntOrString
+	for _, sp := range svc.Spec.Ports {
+		if sp.Port == int32(servicePort) {
+			targetPort = &sp.TargetPort
+			break
+		}
+	}
+	if targetPort == nil {
+		return nil, 0, fmt.Errorf("service %s/%s has no port %d", ns, name, servicePort)
+	}
+
+	selector := labels.SelectorFromSet(svc.Spec.Selector).String()
+	podList, err := coreclient.Pods(ns).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to list pods for service %s/%s: %w", ns, name, err)
+	}
+	sort.Slice(podList.Items, func(i, j int) bool {
+		return podList.Items[i].Name < podList.Items[j].Name
+	})
+
+	var portErr error
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if !isPodReady(pod) {
+			continue
+		}
+		podPort, err := containerPort(pod, *targetPort, servicePort)
+		if err != nil {
+			portErr = err
+			continue
+		}
+		return pod, podPort, nil
+	}
+
+	if portErr != nil {
+		return nil, 0, fmt.Errorf("service %s/%s has no ready pods with its target port: %w", ns, name, portErr)
+	}
+	return nil, 0, fmt.Errorf("service %s/%s has no ready pods matching %s", ns, name, selector)
+}
+
+// containerPort resolves a service targetPort, which may name a container port, to a number.
+func containerPort(pod *corev1.Pod, targetPort intstr.IntOrString, servicePort uint16) (uint16, error) {
+	if targetPort.Type == intstr.Int {
+		if targetPort.IntVal == 0 {
+			// targetPort defaults to the service port
+			return servicePort, nil
+		}
+		return uint16(targetPort.IntVal), nil
+	}
+
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == targetPort.StrVal {
+				return uint16(p.ContainerPort), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("pod %s has no container port named %s", pod.Name, targetPort.StrVal)
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func (r *portForwardRegistry) add(pf PortForward, stopChan chan struct{}) PortForward {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.nextID++
+	pf.ID = strconv.Itoa(r.nextID)
+	r.forwards[pf.ID] = &activePortForward{PortForward: pf, stopChan: stopChan}
+	return pf
+}
+
+func (r *portForwardRegistry) remove(id string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.forwards, id)
+}
+
+func (r *portForwardRegistry) stop(id string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	apf, found := r.forwards[id]
+	if !found {
+		return false
+	}
+	close(apf.stopChan)
+	delete(r.forwards, id)
+	return true
+}
+
+func (r *portForwardRegistry) list() []PortForward {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	pfs := make([]PortForward, 0, len(r.forwards))
+	for _, apf := range r.forwards {
+		pfs = append(pfs, apf.PortForward)
+	}
+	sort.Slice(pfs, func(i, j int) bool {
+		return pfs[i].StartedAt.Before(pfs[j].StartedAt)
+	})
+	return pfs
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func readyPod(name string, labels map[string]string, ports ...corev1.ContainerPort) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "back-end", Labels: labels},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Ports: ports}}},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func TestResolveServicePort(t *testing.T) {
+	labels := map[string]string{"app": "product"}
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "product", Namespace: "back-end"},
+		Spec: corev1.ServiceSpec{
+			Selector: labels,
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 80, TargetPort: intstr.FromString("http")},
+				{Name: "metrics", Port: 9090},
+			},
+		},
+	}
+	notReady := readyPod("product-a", labels, corev1.ContainerPort{Name: "http", ContainerPort: 8080})
+	notReady.Status.Conditions[0].Status = corev1.ConditionFalse
+	// Ready, but from before the port was named
+	oldVersion := readyPod("product-b", labels, corev1.ContainerPort{ContainerPort: 8080})
+	newVersion := readyPod("product-c", labels, corev1.ContainerPort{Name: "http", ContainerPort: 8081})
+	other := readyPod("cart-a", map[string]string{"app": "cart"}, corev1.ContainerPort{Name: "http", ContainerPort: 8082})
+	coreclient := fake.NewSimpleClientset(svc, notReady, oldVersion, newVersion, other).CoreV1()
+	ctx := context.Background()
+
+	pod, port, err := resolveServicePort(ctx, coreclient, "back-end", "product", 80)
+	if err != nil || pod.Name != "product-c" || port != 8081 {
+		t.Errorf("expected product-c:8081, got %v %d %v", pod, port, err)
+	}
+
+	// An unset targetPort is the service port
+	pod, port, err = resolveServicePort(ctx, coreclient, "back-end", "product", 9090)
+	if err != nil || pod.Name != "product-b" || port != 9090 {
+		t.Errorf("expected product-b:9090, got %v %d %v", pod, port, err)
+	}
+
+	if _, _, err := resolveServicePort(ctx, coreclient, "back-end", "product", 443); err == nil || !strings.Contains(err.Error(), "has no port 443") {
+		t.Errorf("expected a missing service port error, got %v", err)
+	}
+
+	onlyOld := fake.NewSimpleClientset(svc, oldVersion).CoreV1()
+	if _, _, err := resolveServicePort(ctx, onlyOld, "back-end", "product", 80); err == nil || !strings.Contains(err.Error(), "no container port named http") {
+		t.Errorf("expected a missing container port error, got %v", err)
+	}
+}
+
+func TestContainerPort(t *testing.T) {
+	pod := readyPod("product-a", nil, corev1.ContainerPort{Name: "http", ContainerPort: 8080})
+	cases := []struct {
+		targetPort intstr.IntOrString
+		expected   uint16
+	}{
+		{intstr.FromInt(8443), 8443},
+		{intstr.IntOrString{}, 80},
+		{intstr.FromString("http"), 8080},
+	}
+	for _, c := range cases {
+		if actual, err := containerPort(pod, c.targetPort, 80); err != nil || actual != c.expected {
+			t.Errorf("%s: expected %d, got %d %v", c.targetPort.String(), c.expected, actual, err)
+		}
+	}
+	if _, err := containerPort(pod, intstr.FromString("grpc"), 80); err == nil {
+		t.Errorf("expected an error for a missing named port")
+	}
+}
+
+func TestPortForwardRegistry(t *testing.T) {
+	r := newPortForwardRegistry()
+	first := make(chan struct{})
+	second := make(chan struct{})
+	now := time.Now()
+	a := r.add(PortForward{Name: "a", StartedAt: now}, first)
+	b := r.add(PortForward{Name: "b", StartedAt: now.Add(time.Second)}, second)
+	if a.ID == b.ID {
+		t.Fatalf("expected unique ids, got %s twice", a.ID)
+	}
+
+	if pfs := r.list(); len(pfs) != 2 || pfs[0].Name != "a" || pfs[1].Name != "b" {
+		t.Errorf("expected a then b, got %+v", pfs)
+	}
+
+	if !r.stop(a.ID) {
+		t.Errorf("expected %s to stop", a.ID)
+	}
+	select {
+	case <-first:
+	default:
+		t.Errorf("expected stop to close the forward's stop channel")
+	}
+	if r.stop(a.ID) {
+		t.Errorf("expected a second stop of %s to fail", a.ID)
+	}
+
+	// An ended forward is forgotten without closing its channel again
+	r.remove(b.ID)
+	if pfs := r.list(); len(pfs) != 0 {
+		t.Errorf("expected no forwards, got %+v", pfs)
+	}
+}
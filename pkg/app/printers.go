@@ -138,6 +138,13 @@ func PrintList(scheme *runtime.Scheme, ar metav1.APIResource, uList *unstructure
 	return printUnstructured(uList)
 }
 
+// PrintObject renders a single object with the table printers.
+func PrintObject(scheme *runtime.Scheme, ar metav1.APIResource, obj *unstructured.Unstructured) (*metav1.Table, error) {
+	uList := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{*obj}}
+	uList.SetGroupVersionKind(toGV(ar).WithKind(ar.Kind + "List"))
+	return PrintList(scheme, ar, uList)
+}
+
 func PrintError(err error) *metav1.Table {
 	return &metav1.Table{
 		ColumnDefinitions: []metav1.TableColumnDefinition{{Name: "Error"}},
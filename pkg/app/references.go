@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cheriot/kubenav/pkg/app/relations"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// findReferencedBy lists the kinds with a relation to obj's kind and keeps the objects that reference or select obj.
+// ie the pods that mount a ConfigMap or the NetworkPolicies that apply to a pod.
+//
+// Only namespaced objects are searched for, within their namespace. Finding the pods of a cluster scoped
+// PriorityClass would mean listing every pod in the cluster.
+func (kc *KubeCluster) findReferencedBy(ctx context.Context, obj *unstructured.Unstructured, apiResource metav1.APIResource, cache relatableCache) ([]relations.HasOneDestination, []error) {
+	referencing, errors := kc.findReferencing(ctx, obj, apiResource, cache)
+	selecting, selectingErrors := kc.findSelecting(ctx, obj, apiResource, cache)
+	return append(referencing, selecting...), append(errors, selectingErrors...)
+}
+
+// relatableCache keeps the kinds listed in one namespace so the searches for an object share them, and callers can
+// use the objects that were found without getting them again. A nil cache lists every time.
+type relatableCache map[schema.GroupKind][]relations.Relatable
+
+func (kc *KubeCluster) listRelatableCached(ctx context.Context, gk schema.GroupKind, nsName string, cache relatableCache) ([]relations.Relatable, []error) {
+	if candidates, found := cache[gk]; found {
+		return candidates, nil
+	}
+
+	// A truncated list is still what the search found, and findScaling prints from it
+	candidates, errors := kc.listRelatable(ctx, gk, nsName)
+	if cache != nil && onlyTruncated(errors) {
+		cache[gk] = candidates
+	}
+	return candidates, errors
+}
+
+// findReferencing finds the objects with a HasOne or References relation to obj.
+func (kc *KubeCluster) findReferencing(ctx context.Context, obj *unstructured.Unstructured, apiResource metav1.APIResource, cache relatableCache) ([]relations.HasOneDestination, []error) {
+	referencing := make([]relations.HasOneDestination, 0)
+	errors := make([]error, 0)
+	if !apiResource.Namespaced {
+		return referencing, errors
+	}
+
+	destination := relations.HasOneDestination{
+		GroupKind: toGK(apiResource),
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}
+	for _, originGK := range relations.ReferencingKinds(destination.GroupKind) {
+		candidates, listErrors := kc.listRelatableCached(ctx, originGK, obj.GetNamespace(), cache)
+		errors = append(errors, listErrors...)
+		referencing = append(referencing, relations.ReferencedBy(destination, originGK, candidates)...)
+	}
+	return referencing, errors
+}
+
+// findSelecting finds the objects with a label selector matching obj.
+func (kc *KubeCluster) findSelecting(ctx context.Context, obj *unstructured.Unstructured, apiResource metav1.APIResource, cache relatableCache) ([]relations.HasOneDestination, []error) {
+	selecting := make([]relations.HasOneDestination, 0)
+	errors := make([]error, 0)
+	if !apiResource.Namespaced {
+		return selecting, errors
+	}
+
+	gk := toGK(apiResource)
+	for _, originGK := range relations.SelectingKinds(gk) {
+		candidates, listErrors := kc.listRelatableCached(ctx, originGK, obj.GetNamespace(), cache)
+		errors = append(errors, listErrors...)
+		selecting = append(selecting, relations.SelectedBy(obj, gk, originGK, candidates)...)
+	}
+	return selecting, errors
+}
+
+// listRelatable lists every gk object in a namespace. Kinds the cluster doesn't serve, or that aren't namespaced, are
+// skipped. Past LIST_LIMIT, the objects that were listed come with a truncatedListError.
+func (kc *KubeCluster) listRelatable(ctx context.Context, gk schema.GroupKind, nsName string) ([]relations.Relatable, []error) {
+	candidates := make([]relations.Relatable, 0)
+	errors := make([]error, 0)
+
+	apiResource, found := findAPIResourceByGK(kc.apiResources, gk)
+	if !found || !apiResource.Namespaced {
+		return candidates, errors
+	}
+
+	uList, err := kc.listUnstructured(ctx, apiResource, nsName, metav1.ListOptions{})
+	if err != nil {
+		return candidates, append(errors, fmt.Errorf("unable to list %s in %s: %w", gk, nsName, err))
+	}
+
+	for i := range uList.Items {
+		candidates = append(candidates, &uList.Items[i])
+	}
+	if isTruncated(uList) {
+		errors = append(errors, &truncatedListError{gk: gk, nsName: nsName})
+	}
+	return candidates, errors
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestFindReferencedByTruncated(t *testing.T) {
+	configMap := &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}, ObjectMeta: metav1.ObjectMeta{Name: "product", Namespace: "back-end"}}
+	pod := readyPod("product-a", nil)
+	pod.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}
+	pod.Spec.Volumes = []corev1.Volume{{
+		Name:         "config",
+		VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "product"}}},
+	}}
+	kc, client := fakeCluster(t, configMap, pod)
+	kc.dynamicClient = truncatingClient{Interface: client, resource: "pods"}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(configMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := findAPIResourceByGK(kc.apiResources, schema.GroupKind{Kind: "ConfigMap"})
+	cache := make(relatableCache)
+	referencedBy, errors := kc.findReferencedBy(context.Background(), &unstructured.Unstructured{Object: content}, r, cache)
+
+	if len(referencedBy) != 1 || referencedBy[0].Name != "product-a" {
+		t.Errorf("expected the listed pod, got %+v", referencedBy)
+	}
+	if len(errors) != 1 || !strings.Contains(errors[0].Error(), "only the first 1000 Pod in back-end were listed") {
+		t.Errorf("expected a truncated pods error, got %v", errors)
+	}
+	// findScaling prints from what was listed
+	if len(cache[podGK]) != 1 {
+		t.Errorf("expected the truncated pods cached, got %v", cache)
+	}
+}
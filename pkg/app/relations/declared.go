@@ -0,0 +1,283 @@
+package relations
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Relations of custom resources, declared in a config file instead of compiled in. ie
+//
+//	relations:
+//	- origin: {group: cert-manager.io, kind: Certificate}
+//	  destination: {kind: Secret}
+//	  field: spec.secretName
+//	- origin: {group: argoproj.io, kind: Rollout}
+//	  destination: {kind: Pod}
+//	  type: selector
+//	  field: spec.selector
+//
+// Fields are dot separated. A [] segment fans out over every element of a list. ie spec.sources[].secretRef.name
+
+const (
+	// field is a destination name. The destination is in the origin's namespace unless namespaceField is set or the
+	// destination is cluster scoped.
+	DeclaredRef = "ref"
+	// field is a label selector. Either a metav1.LabelSelector or a map of labels like a Service's .spec.selector.
+	DeclaredSelector = "selector"
+)
+
+type DeclaredConfig struct {
+	Relations []DeclaredRelation `yaml:"relations"`
+}
+
+type DeclaredRelation struct {
+	Origin      DeclaredGroupKind `yaml:"origin"`
+	Destination DeclaredGroupKind `yaml:"destination"`
+	// ref or selector. Defaults to ref.
+	Type  string `yaml:"type"`
+	Field string `yaml:"field"`
+	// Optional, for refs to another namespace. ie spec.issuerRef.namespace
+	NamespaceField string `yaml:"namespaceField"`
+	// Optional, for refs to a cluster scoped destination
+	ClusterScoped bool `yaml:"clusterScoped"`
+	// Optional, shown with the destination. Defaults to the field.
+	Label string `yaml:"label"`
+}
+
+type DeclaredGroupKind struct {
+	Group string `yaml:"group"`
+	Kind  string `yaml:"kind"`
+}
+
+func (dgk DeclaredGroupKind) GroupKind() schema.GroupKind {
+	return schema.GroupKind{Group: dgk.Group, Kind: dgk.Kind}
+}
+
+var declaredRelations = make([]DeclaredRelation, 0)
+
+// LoadDeclaredRelations reads and registers the relations of a config file. A missing file isn't an error.
+func LoadDeclaredRelations(path string) error {
+	bytes, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read relations config %s: %w", path, err)
+	}
+
+	declared, err := ParseDeclaredRelations(bytes)
+	if err != nil {
+		return fmt.Errorf("unable to parse relations config %s: %w", path, err)
+	}
+	RegisterDeclaredRelations(declared)
+	return nil
+}
+
+func ParseDeclaredRelations(bytes []byte) ([]DeclaredRelation, error) {
+	config := DeclaredConfig{}
+	if err := yaml.Unmarshal(bytes, &config); err != nil {
+		return nil, err
+	}
+
+	for i := range config.Relations {
+		dr := &config.Relations[i]
+		if dr.Type == "" {
+			dr.Type = DeclaredRef
+		}
+		if dr.Origin.Kind == "" || dr.Destination.Kind == "" {
+			return nil, fmt.Errorf("relation %d: origin and destination kinds are required", i)
+		}
+		if dr.Type != DeclaredRef && dr.Type != DeclaredSelector {
+			return nil, fmt.Errorf("relation %d: unknown type %s", i, dr.Type)
+		}
+		if dr.Field == "" {
+			return nil, fmt.Errorf("relation %d: field is required", i)
+		}
+	}
+	return config.Relations, nil
+}
+
+// RegisterDeclaredRelations adds relations alongside the compiled ones. Call it before serving requests.
+func RegisterDeclaredRelations(declared []DeclaredRelation) {
+	declaredRelations = append(declaredRelations, declared...)
+}
+
+// DeclaredRelationsList evaluates the ref relations declared for originGK.
+func DeclaredRelationsList(origin *unstructured.Unstructured, originGK schema.GroupKind) []HasOneDestination {
+	destinations := make([]HasOneDestination, 0)
+	for _, dr := range declaredRelations {
+		if dr.Type != DeclaredRef || dr.Origin.GroupKind() != originGK {
+			continue
+		}
+
+		// Each name is read with the namespace of the same element, ie spec.refs[].name with spec.refs[].namespace
+		prefix, nameField, namespaceField := sharedFanOut(dr.Field, dr.NamespaceField)
+		for _, element := range valuesAt(origin.Object, prefix) {
+			namespace := origin.GetNamespace()
+			if dr.NamespaceField != "" {
+				for _, ns := range valuesAt(element, namespaceField) {
+					if nsStr, ok := ns.(string); ok && nsStr != "" {
+						namespace = nsStr
+						break
+					}
+				}
+			}
+			if dr.ClusterScoped {
+				namespace = ""
+			}
+
+			for _, name := range valuesAt(element, nameField) {
+				nameStr, ok := name.(string)
+				if !ok || nameStr == "" {
+					continue
+				}
+				destinations = append(destinations, HasOneDestination{
+					GroupKind: dr.Destination.GroupKind(),
+					Namespace: namespace,
+					Name:      nameStr,
+					Label:     dr.label(),
+				})
+			}
+		}
+	}
+	return destinations
+}
+
+// DeclaredHasManyList evaluates the selector relations declared for originGK.
+func DeclaredHasManyList(origin *unstructured.Unstructured, originGK schema.GroupKind) []HasManyDestination {
+	destinations := make([]HasManyDestination, 0)
+	for _, dr := range declaredRelations {
+		if dr.Type != DeclaredSelector || dr.Origin.GroupKind() != originGK {
+			continue
+		}
+
+		for _, value := range FieldValues(origin.Object, dr.Field) {
+			selector, err := declaredSelector(value)
+			if err != nil || selector.Empty() {
+				continue
+			}
+			destinations = append(destinations, HasManyDestination{
+				GroupKind:   dr.Destination.GroupKind(),
+				Namespace:   origin.GetNamespace(),
+				QueryParams: map[string]string{LabelSelectorParam: selector.String()},
+			})
+		}
+	}
+	return destinations
+}
+
+func hasDeclared(originGK schema.GroupKind, relationType string) bool {
+	for _, dr := range declaredRelations {
+		if dr.Type == relationType && dr.Origin.GroupKind() == originGK {
+			return true
+		}
+	}
+	return false
+}
+
+func (dr DeclaredRelation) label() string {
+	if dr.Label != "" {
+		return dr.Label
+	}
+	return dr.Field
+}
+
+// declaredSelector accepts a metav1.LabelSelector or a plain map of labels.
+func declaredSelector(value interface{}) (labels.Selector, error) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("selector is a %T, not an object", value)
+	}
+
+	_, hasMatchLabels := m["matchLabels"]
+	_, hasMatchExpressions := m["matchExpressions"]
+	if hasMatchLabels || hasMatchExpressions {
+		ls := &metav1.LabelSelector{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, ls); err != nil {
+			return nil, err
+		}
+		return metav1.LabelSelectorAsSelector(ls)
+	}
+
+	set := labels.Set{}
+	for k, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("label %s is a %T, not a string", k, v)
+		}
+		set[k] = s
+	}
+	return labels.SelectorFromSet(set), nil
+}
+
+// sharedFanOut splits field and namespaceField after the last [] segment they have in common. Returns the common
+// prefix and the rest of each. The prefix is empty when they don't fan out over the same list.
+func sharedFanOut(field string, namespaceField string) (string, string, string) {
+	fieldSegments := strings.Split(normalizeFieldPath(field), ".")
+	namespaceSegments := strings.Split(normalizeFieldPath(namespaceField), ".")
+	shared := 0
+	for i := 0; i < len(fieldSegments) && i < len(namespaceSegments) && fieldSegments[i] == namespaceSegments[i]; i++ {
+		if strings.HasSuffix(fieldSegments[i], "[]") {
+			shared = i + 1
+		}
+	}
+	return strings.Join(fieldSegments[:shared], "."),
+		strings.Join(fieldSegments[shared:], "."),
+		strings.Join(namespaceSegments[shared:], ".")
+}
+
+// valuesAt is FieldValues of any value. An empty path is the value itself, ie the elements of a list of names.
+func valuesAt(v interface{}, path string) []interface{} {
+	if path == "" {
+		return []interface{}{v}
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return FieldValues(m, path)
+}
+
+func normalizeFieldPath(path string) string {
+	path = strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+	return strings.TrimPrefix(path, ".")
+}
+
+// FieldValues of a dot separated path. A segment ending in [] fans out over every element of a list. A leading dot
+// and jsonpath's {} are allowed so `{.spec.secretName}` and `spec.secretName` are the same.
+func FieldValues(obj map[string]interface{}, path string) []interface{} {
+	values := []interface{}{obj}
+	for _, segment := range strings.Split(normalizeFieldPath(path), ".") {
+		fanOut := strings.HasSuffix(segment, "[]")
+		key := strings.TrimSuffix(segment, "[]")
+
+		next := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			child, found := m[key]
+			if !found || child == nil {
+				continue
+			}
+			if !fanOut {
+				next = append(next, child)
+				continue
+			}
+			if list, ok := child.([]interface{}); ok {
+				next = append(next, list...)
+			}
+		}
+		values = next
+	}
+	return values
+}
@@ -0,0 +1,184 @@
+package relations
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Graph of objects and the relations between them, exportable as json, Graphviz DOT or Mermaid.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+	// What's already in Nodes and Edges
+	nodeIDs map[string]bool
+	edges   map[GraphEdge]bool
+}
+
+type GraphNode struct {
+	ID               string `json:"id"`
+	schema.GroupKind `json:"groupKind"`
+	Namespace        string `json:"namespace"`
+	Name             string `json:"name"`
+}
+
+// Kinds of GraphEdge
+const (
+	EdgeOwner     = "owner"
+	EdgeReference = "reference"
+	EdgeSelects   = "selects"
+)
+
+type GraphEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+}
+
+// Graph export formats
+const (
+	GraphJSON    = "json"
+	GraphDOT     = "dot"
+	GraphMermaid = "mermaid"
+)
+
+func NewGraph() *Graph {
+	return &Graph{
+		Nodes:   make([]GraphNode, 0),
+		Edges:   make([]GraphEdge, 0),
+		nodeIDs: make(map[string]bool),
+		edges:   make(map[GraphEdge]bool),
+	}
+}
+
+// NodeID identifies an object in a graph. Cluster scoped objects have an empty namespace.
+func NodeID(d HasOneDestination) string {
+	return fmt.Sprintf("%s/%s/%s", d.GroupKind.String(), d.Namespace, d.Name)
+}
+
+// AddNode adds an object unless it's already in the graph. Returns its ID.
+func (g *Graph) AddNode(d HasOneDestination) string {
+	id := NodeID(d)
+	if g.nodeIDs[id] {
+		return id
+	}
+	g.nodeIDs[id] = true
+	g.Nodes = append(g.Nodes, GraphNode{ID: id, GroupKind: d.GroupKind, Namespace: d.Namespace, Name: d.Name})
+	return id
+}
+
+// AddEdge adds both objects and the relation between them. Duplicate edges are ignored.
+func (g *Graph) AddEdge(from HasOneDestination, to HasOneDestination, kind string, label string) {
+	e := GraphEdge{From: g.AddNode(from), To: g.AddNode(to), Kind: kind, Label: label}
+	if g.edges[e] {
+		return
+	}
+	g.edges[e] = true
+	g.Edges = append(g.Edges, e)
+}
+
+// Sort nodes and edges so exports of the same objects are identical.
+func (g *Graph) Sort() {
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		if g.Edges[i].To != g.Edges[j].To {
+			return g.Edges[i].To < g.Edges[j].To
+		}
+		if g.Edges[i].Kind != g.Edges[j].Kind {
+			return g.Edges[i].Kind < g.Edges[j].Kind
+		}
+		// ie an Ingress's rules to the same Service
+		return g.Edges[i].Label < g.Edges[j].Label
+	})
+}
+
+func (n GraphNode) label() string {
+	return fmt.Sprintf("%s/%s", n.Kind, n.Name)
+}
+
+func (e GraphEdge) label() string {
+	if e.Label == "" {
+		return e.Kind
+	}
+	return fmt.Sprintf("%s: %s", e.Kind, e.Label)
+}
+
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph relations {\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %q [label=%q];\n", n.ID, n.label())
+	}
+	for _, e := range g.Edges {
+		style := ""
+		if e.Kind == EdgeOwner {
+			style = ", style=bold"
+		}
+		fmt.Fprintf(&b, "  %q -> %q [label=%q%s];\n", e.From, e.To, e.label(), style)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid ids can't contain slashes or dots so nodes are numbered.
+func (g *Graph) Mermaid() string {
+	ids := make(map[string]string, len(g.Nodes))
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	for i, n := range g.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", ids[n.ID], mermaidEscape(n.label()))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -->|\"%s\"| %s\n", ids[e.From], mermaidEscape(e.label()), ids[e.To])
+	}
+	return b.String()
+}
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
+
+// GraphKinds are the kinds worth listing to graph a namespace: the origins of every relation and the kinds the built
+// in controllers own.
+func GraphKinds() []schema.GroupKind {
+	kinds := make([]schema.GroupKind, 0)
+	addKind := func(gk schema.GroupKind) {
+		for _, k := range kinds {
+			if k == gk {
+				return
+			}
+		}
+		kinds = append(kinds, gk)
+	}
+
+	for _, hor := range DefaultRegistry.hasOne {
+		addKind(hor.Origin)
+	}
+	for _, rr := range DefaultRegistry.references {
+		addKind(rr.Origin)
+	}
+	for _, sr := range DefaultRegistry.selects {
+		addKind(sr.Origin)
+		addKind(sr.Destination)
+	}
+	for _, dr := range declaredRelations {
+		addKind(dr.Origin.GroupKind())
+	}
+	for parent, children := range childKinds {
+		addKind(parent)
+		for _, child := range children {
+			addKind(child)
+		}
+	}
+
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i].String() < kinds[j].String() })
+	return kinds
+}
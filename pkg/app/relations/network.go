@@ -0,0 +1,158 @@
+package relations
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Traffic flows Ingress -> Service -> EndpointSlice -> Pod. NetworkPolicies select the pods they restrict.
+
+func registerNetworkRelations(r *Registry) {
+	mustRegister(RegisterReferences(r, RelationTyped[*networkingv1.Ingress, *corev1.Service]{
+		Origin:      &networkingv1.Ingress{},
+		Destination: &corev1.Service{},
+		IdentifyDestinations: func(ingress *networkingv1.Ingress) []HasOneDestination {
+			destinations := make([]HasOneDestination, 0)
+			for _, backend := range IngressServiceBackends(ingress) {
+				destinations = append(destinations, HasOneDestination{
+					Namespace: ingress.Namespace,
+					Name:      backend.Service.Name,
+					Label:     backend.Label(),
+				})
+			}
+			return destinations
+		},
+	}))
+
+	mustRegister(RegisterReferences(r, RelationTyped[*discoveryv1.EndpointSlice, *corev1.Pod]{
+		Origin:      &discoveryv1.EndpointSlice{},
+		Destination: &corev1.Pod{},
+		IdentifyDestinations: func(slice *discoveryv1.EndpointSlice) []HasOneDestination {
+			destinations := make([]HasOneDestination, 0)
+			for _, endpoint := range slice.Endpoints {
+				if endpoint.TargetRef == nil || endpoint.TargetRef.Kind != podGK.Kind {
+					continue
+				}
+				label := "ready"
+				if !IsEndpointReady(endpoint) {
+					label = "not ready"
+				}
+				destinations = append(destinations, HasOneDestination{
+					Namespace: endpoint.TargetRef.Namespace,
+					Name:      endpoint.TargetRef.Name,
+					Label:     label,
+				})
+			}
+			return destinations
+		},
+	}))
+
+	// The EndpointSlice controller labels the slices it manages with their service's name
+	mustRegister(RegisterHasMany(r, RelationTyped[*corev1.Service, *discoveryv1.EndpointSlice]{
+		Origin:      &corev1.Service{},
+		Destination: &discoveryv1.EndpointSlice{},
+		IsApplicable: func(svc *corev1.Service) bool {
+			return svc.Spec.Type != corev1.ServiceTypeExternalName
+		},
+		ExtractParams: func(svc *corev1.Service) map[string]string {
+			return map[string]string{
+				LabelSelectorParam: labels.SelectorFromSet(map[string]string{discoveryv1.LabelServiceName: svc.Name}).String(),
+			}
+		},
+	}))
+
+	// An empty podSelector selects every pod in the namespace, so unlike workloads it's always applicable
+	mustRegister(RegisterHasMany(r, RelationTyped[*networkingv1.NetworkPolicy, *corev1.Pod]{
+		Origin:      &networkingv1.NetworkPolicy{},
+		Destination: &corev1.Pod{},
+		IsApplicable: func(np *networkingv1.NetworkPolicy) bool {
+			_, err := metav1.LabelSelectorAsSelector(&np.Spec.PodSelector)
+			return err == nil
+		},
+		ExtractParams: func(np *networkingv1.NetworkPolicy) map[string]string {
+			selector, _ := metav1.LabelSelectorAsSelector(&np.Spec.PodSelector)
+			return map[string]string{
+				LabelSelectorParam: selector.String(),
+			}
+		},
+	}))
+
+	mustRegister(RegisterSelects(r, RelationTyped[*networkingv1.NetworkPolicy, *corev1.Pod]{
+		Origin:      &networkingv1.NetworkPolicy{},
+		Destination: &corev1.Pod{},
+		Selects:     NetworkPolicySelectsPod,
+	}))
+}
+
+// NetworkPolicySelectsPod when the policy applies to the pod.
+func NetworkPolicySelectsPod(np *networkingv1.NetworkPolicy, pod *corev1.Pod) bool {
+	if np.Namespace != pod.Namespace {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&np.Spec.PodSelector)
+	return err == nil && selector.Matches(labels.Set(pod.Labels))
+}
+
+// IngressServiceBackend is one route of an Ingress to a Service. Backends that reference other resources aren't
+// included.
+type IngressServiceBackend struct {
+	// Empty for the default backend. ie every host and path not matched by a rule.
+	Host    string                             `json:"host"`
+	Path    string                             `json:"path"`
+	Default bool                               `json:"default"`
+	Service networkingv1.IngressServiceBackend `json:"service"`
+}
+
+func (b IngressServiceBackend) Label() string {
+	port := b.Service.Port.Name
+	if port == "" {
+		port = fmt.Sprint(b.Service.Port.Number)
+	}
+	if b.Default {
+		return fmt.Sprintf("default backend -> %s", port)
+	}
+
+	host := b.Host
+	if host == "" {
+		host = "*"
+	}
+	return fmt.Sprintf("%s%s -> %s", host, b.Path, port)
+}
+
+func IngressServiceBackends(ingress *networkingv1.Ingress) []IngressServiceBackend {
+	backends := make([]IngressServiceBackend, 0)
+	if ingress.Spec.DefaultBackend != nil && ingress.Spec.DefaultBackend.Service != nil {
+		backends = append(backends, IngressServiceBackend{
+			Default: true,
+			Service: *ingress.Spec.DefaultBackend.Service,
+		})
+	}
+
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			if path.Backend.Service == nil {
+				continue
+			}
+			backends = append(backends, IngressServiceBackend{
+				Host:    rule.Host,
+				Path:    path.Path,
+				Service: *path.Backend.Service,
+			})
+		}
+	}
+
+	return backends
+}
+
+// IsEndpointReady follows the EndpointConditions convention that an unknown ready state is ready.
+func IsEndpointReady(endpoint discoveryv1.Endpoint) bool {
+	return endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready
+}
@@ -0,0 +1,135 @@
+package relations
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// https://kubernetes.io/docs/concepts/overview/working-with-objects/owners-dependents/
+// ownerReferences:
+//   - apiVersion: apps/v1
+//     blockOwnerDeletion: true
+//     controller: true
+//     kind: ReplicaSet
+//     name: frontend
+//     uid: f391f6db-bb9b-4c09-ae74-6a1f77f3d5cf
+
+// OwnerList reads metadata.ownerReferences, so it works for any kind, typed or unstructured. The controller, if any,
+// is first.
+func OwnerList(origin runtime.Object) []HasOneDestination {
+	destinations := make([]HasOneDestination, 0)
+
+	accessor, err := meta.Accessor(origin)
+	if err != nil {
+		return destinations
+	}
+
+	for _, or := range accessor.GetOwnerReferences() {
+		gv, err := schema.ParseGroupVersion(or.APIVersion)
+		if err != nil {
+			continue
+		}
+		destinations = append(destinations, HasOneDestination{
+			GroupKind: schema.GroupKind{Group: gv.Group, Kind: or.Kind},
+			// Owners are in the same namespace or cluster scoped. A namespace is ignored when getting a cluster
+			// scoped object.
+			Namespace:  accessor.GetNamespace(),
+			Name:       or.Name,
+			Controller: or.Controller != nil && *or.Controller,
+		})
+	}
+
+	sort.SliceStable(destinations, func(i, j int) bool {
+		return destinations[i].Controller && !destinations[j].Controller
+	})
+	return destinations
+}
+
+var (
+	podGK                = schema.GroupKind{Kind: "Pod"}
+	serviceGK            = schema.GroupKind{Kind: "Service"}
+	configMapGK          = schema.GroupKind{Kind: "ConfigMap"}
+	secretGK             = schema.GroupKind{Kind: "Secret"}
+	pvcGK                = schema.GroupKind{Kind: "PersistentVolumeClaim"}
+	deploymentGK         = schema.GroupKind{Group: "apps", Kind: "Deployment"}
+	replicaSetGK         = schema.GroupKind{Group: "apps", Kind: "ReplicaSet"}
+	statefulSetGK        = schema.GroupKind{Group: "apps", Kind: "StatefulSet"}
+	daemonSetGK          = schema.GroupKind{Group: "apps", Kind: "DaemonSet"}
+	controllerRevisionGK = schema.GroupKind{Group: "apps", Kind: "ControllerRevision"}
+	jobGK                = schema.GroupKind{Group: "batch", Kind: "Job"}
+	cronJobGK            = schema.GroupKind{Group: "batch", Kind: "CronJob"}
+	endpointSliceGK      = schema.GroupKind{Group: "discovery.k8s.io", Kind: "EndpointSlice"}
+)
+
+// Listing every kind to find an object's children is too expensive, so only look where the built in controllers put
+// them.
+var childKinds = map[schema.GroupKind][]schema.GroupKind{
+	deploymentGK:  {replicaSetGK},
+	replicaSetGK:  {podGK},
+	statefulSetGK: {podGK, controllerRevisionGK, pvcGK},
+	daemonSetGK:   {podGK, controllerRevisionGK},
+	cronJobGK:     {jobGK},
+	jobGK:         {podGK},
+	serviceGK:     {endpointSliceGK},
+}
+
+// Operators commonly create these for their custom resources.
+var defaultCustomResourceChildKinds = []schema.GroupKind{
+	deploymentGK, statefulSetGK, daemonSetGK, jobGK, podGK, serviceGK, configMapGK, secretGK, pvcGK,
+}
+
+// ChildKinds are the kinds that may have an ownerReference to an object of kind parent.
+func ChildKinds(parent schema.GroupKind, isCustomResource bool) []schema.GroupKind {
+	if kinds, found := childKinds[parent]; found {
+		return kinds
+	}
+	if isCustomResource {
+		return defaultCustomResourceChildKinds
+	}
+	return nil
+}
+
+// ChildList filters candidates to those with an ownerReference to parent.
+func ChildList(parent runtime.Object, candidates []runtime.Object) []HasOneDestination {
+	children := make([]HasOneDestination, 0)
+
+	parentAccessor, err := meta.Accessor(parent)
+	if err != nil {
+		return children
+	}
+
+	for _, candidate := range candidates {
+		or := OwnerReferenceTo(candidate, parentAccessor.GetUID())
+		if or == nil {
+			continue
+		}
+		// OwnerReferenceTo found the accessor
+		accessor, _ := meta.Accessor(candidate)
+		children = append(children, HasOneDestination{
+			GroupKind:  candidate.GetObjectKind().GroupVersionKind().GroupKind(),
+			Namespace:  accessor.GetNamespace(),
+			Name:       accessor.GetName(),
+			Controller: or.Controller != nil && *or.Controller,
+		})
+	}
+	return children
+}
+
+// OwnerReferenceTo returns obj's ownerReference to the owner with uid or nil if there isn't one.
+func OwnerReferenceTo(obj runtime.Object, uid types.UID) *metav1.OwnerReference {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil
+	}
+	for _, or := range accessor.GetOwnerReferences() {
+		if or.UID == uid {
+			return &or
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package relations
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// RoleBinding -> Role or ClusterRole, ClusterRoleBinding -> ClusterRole. Both reference their ServiceAccount
+// subjects. Users and groups aren't objects so there's nothing to link to.
+
+func registerRBACRelations(r *Registry) {
+	// A RoleBinding grants either a Role in its own namespace or a ClusterRole's rules within its namespace
+	mustRegister(RegisterHasOne(r, RelationTyped[*rbacv1.RoleBinding, *rbacv1.Role]{
+		Origin:      &rbacv1.RoleBinding{},
+		Destination: &rbacv1.Role{},
+		IsApplicable: func(rb *rbacv1.RoleBinding) bool {
+			return rb.RoleRef.Kind == "Role"
+		},
+		IdentifyDestination: func(rb *rbacv1.RoleBinding) HasOneDestination {
+			return HasOneDestination{Namespace: rb.Namespace, Name: rb.RoleRef.Name}
+		},
+	}))
+
+	mustRegister(RegisterHasOne(r, RelationTyped[*rbacv1.RoleBinding, *rbacv1.ClusterRole]{
+		Origin:      &rbacv1.RoleBinding{},
+		Destination: &rbacv1.ClusterRole{},
+		IsApplicable: func(rb *rbacv1.RoleBinding) bool {
+			return rb.RoleRef.Kind == "ClusterRole"
+		},
+		IdentifyDestination: func(rb *rbacv1.RoleBinding) HasOneDestination {
+			return HasOneDestination{Name: rb.RoleRef.Name}
+		},
+	}))
+
+	mustRegister(RegisterHasOne(r, RelationTyped[*rbacv1.ClusterRoleBinding, *rbacv1.ClusterRole]{
+		Origin:      &rbacv1.ClusterRoleBinding{},
+		Destination: &rbacv1.ClusterRole{},
+		IsApplicable: func(crb *rbacv1.ClusterRoleBinding) bool {
+			return crb.RoleRef.Kind == "ClusterRole"
+		},
+		IdentifyDestination: func(crb *rbacv1.ClusterRoleBinding) HasOneDestination {
+			return HasOneDestination{Name: crb.RoleRef.Name}
+		},
+	}))
+
+	mustRegister(RegisterReferences(r, RelationTyped[*rbacv1.RoleBinding, *corev1.ServiceAccount]{
+		Origin:      &rbacv1.RoleBinding{},
+		Destination: &corev1.ServiceAccount{},
+		IdentifyDestinations: func(rb *rbacv1.RoleBinding) []HasOneDestination {
+			return serviceAccountSubjects(rb.Namespace, rb.Subjects)
+		},
+	}))
+
+	mustRegister(RegisterReferences(r, RelationTyped[*rbacv1.ClusterRoleBinding, *corev1.ServiceAccount]{
+		Origin:      &rbacv1.ClusterRoleBinding{},
+		Destination: &corev1.ServiceAccount{},
+		IdentifyDestinations: func(crb *rbacv1.ClusterRoleBinding) []HasOneDestination {
+			return serviceAccountSubjects("", crb.Subjects)
+		},
+	}))
+}
+
+func serviceAccountSubjects(bindingNamespace string, subjects []rbacv1.Subject) []HasOneDestination {
+	destinations := make([]HasOneDestination, 0)
+	for _, s := range subjects {
+		if s.Kind != rbacv1.ServiceAccountKind {
+			continue
+		}
+		destinations = append(destinations, HasOneDestination{
+			Namespace: SubjectNamespace(s, bindingNamespace),
+			Name:      s.Name,
+		})
+	}
+	return destinations
+}
+
+// SubjectNamespace of a ServiceAccount subject. Older RoleBindings may leave it empty to mean the binding's namespace.
+func SubjectNamespace(s rbacv1.Subject, bindingNamespace string) string {
+	if s.Kind != rbacv1.ServiceAccountKind {
+		return ""
+	}
+	if s.Namespace == "" {
+		return bindingNamespace
+	}
+	return s.Namespace
+}
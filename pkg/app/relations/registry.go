@@ -0,0 +1,226 @@
+package relations
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Registry holds the relations between kinds. The go types of every origin and destination must be in its scheme,
+// which is also used to convert unstructured objects to those types.
+type Registry struct {
+	scheme     *runtime.Scheme
+	hasOne     []HasOneRelation
+	references []ReferencesRelation
+	hasMany    []HasManyRelations
+	selects    []SelectsRelation
+}
+
+func NewRegistry(scheme *runtime.Scheme) *Registry {
+	return &Registry{
+		scheme:     scheme,
+		hasOne:     make([]HasOneRelation, 0),
+		references: make([]ReferencesRelation, 0),
+		hasMany:    make([]HasManyRelations, 0),
+		selects:    make([]SelectsRelation, 0),
+	}
+}
+
+// DefaultRegistry has the built in relations and is what every lookup in this package uses. Other packages may
+// register more, ie from an init func, but not once requests are being served.
+var DefaultRegistry = newDefaultRegistry()
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry(newRelationsScheme())
+	registerCoreRelations(r)
+	registerNetworkRelations(r)
+	registerVolumeRelations(r)
+	registerRBACRelations(r)
+	registerScalingRelations(r)
+	return r
+}
+
+// AddToScheme registers the go types of a third party API. ie a generated clientset's AddToScheme.
+func (r *Registry) AddToScheme(addToScheme func(*runtime.Scheme) error) error {
+	return addToScheme(r.scheme)
+}
+
+// RelationTyped describes a relation from origin type T to destination type U. Register it with RegisterHasOne,
+// RegisterReferences, RegisterHasMany or RegisterSelects, which each require one of the identifying funcs. The
+// registered relation only calls them with a T, so they never need a type assertion.
+type RelationTyped[T runtime.Object, U runtime.Object] struct {
+	// Zero values of the go types. ie &corev1.Pod{}
+	Origin      T
+	Destination U
+	// Optional. The relation applies to every T when nil.
+	IsApplicable func(T) bool
+
+	// RegisterHasOne. The GroupKind is always Destination's.
+	IdentifyDestination func(T) HasOneDestination
+	// RegisterReferences. The GroupKinds are always Destination's.
+	IdentifyDestinations func(T) []HasOneDestination
+	// RegisterHasMany. Search params of the Destination search page.
+	ExtractParams func(T) map[string]string
+	// RegisterHasMany. Search every namespace instead of the origin's.
+	AllNamespaces bool
+	// RegisterSelects
+	Selects func(T, U) bool
+}
+
+func RegisterHasOne[T runtime.Object, U runtime.Object](r *Registry, rt RelationTyped[T, U]) error {
+	if rt.IdentifyDestination == nil {
+		return fmt.Errorf("unable to register has one relation %T -> %T: IdentifyDestination is required", rt.Origin, rt.Destination)
+	}
+	originGK, destinationGK, err := r.groupKinds(rt.Origin, rt.Destination)
+	if err != nil {
+		return err
+	}
+
+	isApplicable := rt.isApplicable()
+	r.hasOne = append(r.hasOne, HasOneRelation{
+		Origin:      originGK,
+		Destination: destinationGK,
+		IsApplicable: func(origin runtime.Object) bool {
+			o, ok := origin.(T)
+			return ok && isApplicable(o)
+		},
+		IdentifyDestination: func(origin runtime.Object) HasOneDestination {
+			o, ok := origin.(T)
+			if !ok {
+				return HasOneDestination{GroupKind: destinationGK}
+			}
+			d := rt.IdentifyDestination(o)
+			d.GroupKind = destinationGK
+			return d
+		},
+	})
+	return nil
+}
+
+func RegisterReferences[T runtime.Object, U runtime.Object](r *Registry, rt RelationTyped[T, U]) error {
+	if rt.IdentifyDestinations == nil {
+		return fmt.Errorf("unable to register references relation %T -> %T: IdentifyDestinations is required", rt.Origin, rt.Destination)
+	}
+	originGK, destinationGK, err := r.groupKinds(rt.Origin, rt.Destination)
+	if err != nil {
+		return err
+	}
+
+	isApplicable := rt.isApplicable()
+	r.references = append(r.references, ReferencesRelation{
+		Origin:      originGK,
+		Destination: destinationGK,
+		IdentifyDestinations: func(origin runtime.Object) []HasOneDestination {
+			o, ok := origin.(T)
+			if !ok || !isApplicable(o) {
+				return []HasOneDestination{}
+			}
+			destinations := rt.IdentifyDestinations(o)
+			for i := range destinations {
+				destinations[i].GroupKind = destinationGK
+			}
+			return destinations
+		},
+	})
+	return nil
+}
+
+func RegisterHasMany[T runtime.Object, U runtime.Object](r *Registry, rt RelationTyped[T, U]) error {
+	if rt.ExtractParams == nil {
+		return fmt.Errorf("unable to register has many relation %T -> %T: ExtractParams is required", rt.Origin, rt.Destination)
+	}
+	originGK, destinationGK, err := r.groupKinds(rt.Origin, rt.Destination)
+	if err != nil {
+		return err
+	}
+
+	isApplicable := rt.isApplicable()
+	r.hasMany = append(r.hasMany, HasManyRelations{
+		Origin:      originGK,
+		Destination: destinationGK,
+		IsApplicable: func(origin runtime.Object) bool {
+			o, ok := origin.(T)
+			return ok && isApplicable(o)
+		},
+		QueryParams: func(origin runtime.Object) map[string]string {
+			o, ok := origin.(T)
+			if !ok {
+				return map[string]string{}
+			}
+			return rt.ExtractParams(o)
+		},
+		AllNamespaces: rt.AllNamespaces,
+	})
+	return nil
+}
+
+func RegisterSelects[T runtime.Object, U runtime.Object](r *Registry, rt RelationTyped[T, U]) error {
+	if rt.Selects == nil {
+		return fmt.Errorf("unable to register selects relation %T -> %T: Selects is required", rt.Origin, rt.Destination)
+	}
+	originGK, destinationGK, err := r.groupKinds(rt.Origin, rt.Destination)
+	if err != nil {
+		return err
+	}
+
+	isApplicable := rt.isApplicable()
+	r.selects = append(r.selects, SelectsRelation{
+		Origin:      originGK,
+		Destination: destinationGK,
+		Selects: func(origin runtime.Object, destination runtime.Object) bool {
+			o, ok := origin.(T)
+			if !ok || !isApplicable(o) {
+				return false
+			}
+			d, ok := destination.(U)
+			return ok && rt.Selects(o, d)
+		},
+	})
+	return nil
+}
+
+func (rt RelationTyped[T, U]) isApplicable() func(T) bool {
+	if rt.IsApplicable == nil {
+		return func(T) bool { return true }
+	}
+	return rt.IsApplicable
+}
+
+// groupKinds validates that origin and destination are go types of the registry's scheme.
+func (r *Registry) groupKinds(origin runtime.Object, destination runtime.Object) (schema.GroupKind, schema.GroupKind, error) {
+	originGK, err := r.groupKind(origin)
+	if err != nil {
+		return schema.GroupKind{}, schema.GroupKind{}, fmt.Errorf("unable to register relation %T -> %T: %w", origin, destination, err)
+	}
+	destinationGK, err := r.groupKind(destination)
+	if err != nil {
+		return schema.GroupKind{}, schema.GroupKind{}, fmt.Errorf("unable to register relation %T -> %T: %w", origin, destination, err)
+	}
+	return originGK, destinationGK, nil
+}
+
+func (r *Registry) groupKind(obj runtime.Object) (schema.GroupKind, error) {
+	if _, isUnstructured := obj.(*unstructured.Unstructured); isUnstructured {
+		return schema.GroupKind{}, fmt.Errorf("unstructured objects have no go type, declare the relation instead")
+	}
+	gvks, _, err := r.scheme.ObjectKinds(obj)
+	if err != nil {
+		return schema.GroupKind{}, err
+	}
+	gk := gvks[0].GroupKind()
+	for _, gvk := range gvks[1:] {
+		if gvk.GroupKind() != gk {
+			return schema.GroupKind{}, fmt.Errorf("%T is registered as several kinds: %v", obj, gvks)
+		}
+	}
+	return gk, nil
+}
+
+// mustRegister is for the built in relations. A mistake in them is a bug, not a runtime condition.
+func mustRegister(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
@@ -1,14 +1,21 @@
 package relations
 
 import (
-	"fmt"
-	"strings"
-
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-
-	// rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -19,7 +26,7 @@ import (
 
 // * transitive relations
 //   * deployment to pods
-//   * ingress to pods
+//   * ingress to pods (TrafficPath in the app package)
 
 // Operations
 // 1. current object -> list of {GroupKind, (search params | object [namespace] name)}
@@ -28,104 +35,157 @@ type Relatable interface {
 	runtime.Object
 }
 
-var relations = BuildRelations()
-
-func BuildRelations() []HasOneRelation {
+func newRelationsScheme() *runtime.Scheme {
 	scheme := runtime.NewScheme()
 	corev1.AddToScheme(scheme)
 	appsv1.AddToScheme(scheme)
+	autoscalingv1.AddToScheme(scheme)
+	autoscalingv2.AddToScheme(scheme)
+	batchv1.AddToScheme(scheme)
+	discoveryv1.AddToScheme(scheme)
+	networkingv1.AddToScheme(scheme)
+	policyv1.AddToScheme(scheme)
+	rbacv1.AddToScheme(scheme)
+	schedulingv1.AddToScheme(scheme)
+	storagev1.AddToScheme(scheme)
+	return scheme
+}
 
-	podGK := objectKind(&corev1.Pod{}, scheme)
-	nodeGK := objectKind(&corev1.Node{}, scheme)
-	rsGK := objectKind(&appsv1.ReplicaSet{}, scheme)
-	deploymentGK := objectKind(&appsv1.Deployment{}, scheme)
-
-	var podHasOneNode = HasOneRelation{
-		Origin:      podGK,
-		Destination: nodeGK,
-		IsApplicable: func(origin runtime.Object) bool {
-			pod := origin.(*corev1.Pod)
+func registerCoreRelations(r *Registry) {
+	mustRegister(RegisterHasOne(r, RelationTyped[*corev1.Pod, *corev1.Node]{
+		Origin:      &corev1.Pod{},
+		Destination: &corev1.Node{},
+		IsApplicable: func(pod *corev1.Pod) bool {
 			return pod.Spec.NodeName != ""
 		},
-		IdentifyDestination: func(origin runtime.Object) HasOneDestination {
-			pod := origin.(*corev1.Pod)
-			return HasOneDestination{
-				GroupKind: nodeGK,
-				Name:      pod.Spec.NodeName,
-			}
+		IdentifyDestination: func(pod *corev1.Pod) HasOneDestination {
+			return HasOneDestination{Name: pod.Spec.NodeName}
 		},
-	}
+	}))
 
-	var podHasOneReplicaSet = HasOneRelation{
-		// https://kubernetes.io/docs/concepts/overview/working-with-objects/owners-dependents/
-		// ownerReferences:
-		//   - apiVersion: apps/v1
-		//     blockOwnerDeletion: true
-		//     controller: true
-		//     kind: ReplicaSet
-		//     name: frontend
-		//     uid: f391f6db-bb9b-4c09-ae74-6a1f77f3d5cf
-		Origin:      podGK,
-		Destination: rsGK,
-		IsApplicable: func(origin runtime.Object) bool {
-			pod := origin.(*corev1.Pod)
-
-			return nil != ownerByGK(pod.OwnerReferences, rsGK)
+	mustRegister(RegisterHasMany(r, RelationTyped[*corev1.Service, *corev1.Pod]{
+		Origin:      &corev1.Service{},
+		Destination: &corev1.Pod{},
+		IsApplicable: func(svc *corev1.Service) bool {
+			return len(svc.Spec.Selector) > 0
 		},
-		IdentifyDestination: func(origin runtime.Object) HasOneDestination {
-			pod := origin.(*corev1.Pod)
-
-			or := ownerByGK(pod.OwnerReferences, rsGK)
-			return HasOneDestination{
-				GroupKind: rsGK,
-				Namespace: pod.Namespace,
-				Name:      or.Name,
+		ExtractParams: func(svc *corev1.Service) map[string]string {
+			return map[string]string{
+				LabelSelectorParam: labels.SelectorFromSet(svc.Spec.Selector).String(),
 			}
 		},
-	}
-
-	var rsHasOneDeployment = HasOneRelation{
-		Origin:      rsGK,
-		Destination: deploymentGK,
-		IsApplicable: func(origin runtime.Object) bool {
-			rs := origin.(*appsv1.ReplicaSet)
+	}))
 
-			return nil != ownerByGK(rs.OwnerReferences, deploymentGK)
-		},
-		IdentifyDestination: func(origin runtime.Object) HasOneDestination {
-			rs := origin.(*appsv1.ReplicaSet)
-			or := ownerByGK(rs.OwnerReferences, deploymentGK)
-
-			return HasOneDestination{
-				GroupKind: deploymentGK,
-				Namespace: rs.Namespace,
-				Name:      or.Name,
+	mustRegister(RegisterHasMany(r, RelationTyped[*corev1.Node, *corev1.Pod]{
+		Origin:      &corev1.Node{},
+		Destination: &corev1.Pod{},
+		ExtractParams: func(node *corev1.Node) map[string]string {
+			return map[string]string{
+				FieldSelectorParam: fields.OneTermEqualSelector("spec.nodeName", node.Name).String(),
 			}
 		},
-	}
+		AllNamespaces: true,
+	}))
 
-	return []HasOneRelation{podHasOneNode, podHasOneReplicaSet, rsHasOneDeployment}
-}
+	// Workloads select the pods they manage with spec.selector
+	registerLabelSelectorHasManyPods(r, &appsv1.Deployment{}, func(d *appsv1.Deployment) *metav1.LabelSelector { return d.Spec.Selector })
+	registerLabelSelectorHasManyPods(r, &appsv1.ReplicaSet{}, func(rs *appsv1.ReplicaSet) *metav1.LabelSelector { return rs.Spec.Selector })
+	registerLabelSelectorHasManyPods(r, &appsv1.StatefulSet{}, func(sts *appsv1.StatefulSet) *metav1.LabelSelector { return sts.Spec.Selector })
+	registerLabelSelectorHasManyPods(r, &appsv1.DaemonSet{}, func(ds *appsv1.DaemonSet) *metav1.LabelSelector { return ds.Spec.Selector })
+	registerLabelSelectorHasManyPods(r, &batchv1.Job{}, func(job *batchv1.Job) *metav1.LabelSelector { return job.Spec.Selector })
 
-func objectKind(obj runtime.Object, scheme *runtime.Scheme) schema.GroupKind {
-	gvks, _, err := scheme.ObjectKinds(obj)
-	if err != nil || len(gvks) > 1 {
-		panic(fmt.Sprintf("unexpected ObjectKinds: %+v for %+v", gvks, obj))
-	}
-	return gvks[0].GroupKind()
+	mustRegister(RegisterSelects(r, RelationTyped[*corev1.Service, *corev1.Pod]{
+		Origin:      &corev1.Service{},
+		Destination: &corev1.Pod{},
+		IsApplicable: func(svc *corev1.Service) bool {
+			return len(svc.Spec.Selector) > 0
+		},
+		Selects: func(svc *corev1.Service, pod *corev1.Pod) bool {
+			return svc.Namespace == pod.Namespace && labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(pod.Labels))
+		},
+	}))
 }
 
+// RelationsList evaluates the compiled and declared relations of origin. origin may be a go type or unstructured.
+// Unstructured objects that can't be converted to their go type only get their declared relations.
 func RelationsList(origin Relatable, originGK schema.GroupKind) []HasOneDestination {
 	destinations := make([]HasOneDestination, 0)
-	for _, hor := range relations {
-		if hor.Origin == originGK && hor.IsApplicable(origin) {
-			destinations = append(destinations, hor.IdentifyDestination(origin))
+	if typed := typedOrNil(origin); typed != nil {
+		for _, hor := range DefaultRegistry.hasOne {
+			if hor.Origin == originGK && hor.IsApplicable(typed) {
+				destinations = append(destinations, hor.IdentifyDestination(typed))
+			}
+		}
+		for _, rr := range DefaultRegistry.references {
+			if rr.Origin == originGK {
+				destinations = append(destinations, rr.IdentifyDestinations(typed)...)
+			}
+		}
+	}
+
+	if hasDeclared(originGK, DeclaredRef) {
+		if u, err := asUnstructured(origin); err == nil {
+			destinations = append(destinations, DeclaredRelationsList(u, originGK)...)
 		}
 	}
 
 	return destinations
 }
 
+// ReferencingKinds are the origin kinds of every HasOne and References relation to destination.
+func ReferencingKinds(destination schema.GroupKind) []schema.GroupKind {
+	kinds := make([]schema.GroupKind, 0)
+	addKind := func(gk schema.GroupKind) {
+		for _, k := range kinds {
+			if k == gk {
+				return
+			}
+		}
+		kinds = append(kinds, gk)
+	}
+
+	for _, hor := range DefaultRegistry.hasOne {
+		if hor.Destination == destination {
+			addKind(hor.Origin)
+		}
+	}
+	for _, rr := range DefaultRegistry.references {
+		if rr.Destination == destination {
+			addKind(rr.Origin)
+		}
+	}
+	for _, dr := range declaredRelations {
+		if dr.Type == DeclaredRef && dr.Destination.GroupKind() == destination {
+			addKind(dr.Origin.GroupKind())
+		}
+	}
+	return kinds
+}
+
+// ReferencedBy filters candidates, all of kind candidateGK, to those with a relation to destination. ie the pods
+// that mount a ConfigMap. The label of the returned origins is the label of their relation.
+func ReferencedBy(destination HasOneDestination, candidateGK schema.GroupKind, candidates []Relatable) []HasOneDestination {
+	origins := make([]HasOneDestination, 0)
+	for _, candidate := range candidates {
+		accessor, err := meta.Accessor(candidate)
+		if err != nil {
+			continue
+		}
+		for _, d := range RelationsList(candidate, candidateGK) {
+			if d.GroupKind == destination.GroupKind && d.Namespace == destination.Namespace && d.Name == destination.Name {
+				origins = append(origins, HasOneDestination{
+					GroupKind: candidateGK,
+					Namespace: accessor.GetNamespace(),
+					Name:      accessor.GetName(),
+					Label:     d.Label,
+				})
+				break
+			}
+		}
+	}
+	return origins
+}
+
 func ReverseHasOneRelation(destination runtime.Object, ns string, sr HasOneRelation, possibleOrigins []runtime.Object) []runtime.Object {
 	d := HasOneDestination{
 		GroupKind: objGK(destination),
@@ -142,11 +202,11 @@ func ReverseHasOneRelation(destination runtime.Object, ns string, sr HasOneRelat
 }
 
 // * *:1 relations
-//   * ownerRef
-//   * ingress to backend
+//   * ownerRef (owners.go)
+//   * ingress to backend (network.go)
 //   * pod to node (spec.nodeName)
-//   * [cluster] role binding -> service account
-//   * [cluster] role binding -> role
+//   * [cluster] role binding -> service account (rbac.go)
+//   * [cluster] role binding -> role (rbac.go)
 
 // The Origin object  contains an identifier for the one and only Destination object it has this relationship with.
 type HasOneRelation struct {
@@ -161,6 +221,70 @@ type HasOneDestination struct {
 	schema.GroupKind `json:"groupKind"`
 	Namespace        string `json:"namespace"`
 	Name             string `json:"name"`
+	// The destination is the origin's managing controller. Only set for owner relations.
+	Controller bool `json:"controller"`
+	// The origin works without the destination. ie a pod's ConfigMap volume with optional: true
+	Optional bool `json:"optional"`
+	// Distinguishes several destinations from one origin. ie the host and path of an ingress rule
+	Label string `json:"label"`
+}
+
+// The Origin object contains identifiers for any number of Destination objects. ie an Ingress's backends.
+type ReferencesRelation struct {
+	Origin               schema.GroupKind                         `json:"origin"`
+	Destination          schema.GroupKind                         `json:"destination"`
+	IdentifyDestinations func(runtime.Object) []HasOneDestination `json:"-"`
+}
+
+// The Origin object selects Destination objects by their labels. The Destination doesn't name its origins so finding
+// them means evaluating every candidate origin. ie the NetworkPolicies that apply to a pod.
+type SelectsRelation struct {
+	Origin      schema.GroupKind                                             `json:"origin"`
+	Destination schema.GroupKind                                             `json:"destination"`
+	Selects     func(origin runtime.Object, destination runtime.Object) bool `json:"-"`
+}
+
+// SelectingKinds are the origin kinds of every SelectsRelation to destination.
+func SelectingKinds(destination schema.GroupKind) []schema.GroupKind {
+	kinds := make([]schema.GroupKind, 0)
+	for _, sr := range DefaultRegistry.selects {
+		if sr.Destination == destination {
+			kinds = append(kinds, sr.Origin)
+		}
+	}
+	return kinds
+}
+
+// SelectedBy filters candidates, all of kind candidateGK, to those that select destination. Both may be go types or
+// unstructured.
+func SelectedBy(destination Relatable, destinationGK schema.GroupKind, candidateGK schema.GroupKind, candidates []Relatable) []HasOneDestination {
+	origins := make([]HasOneDestination, 0)
+	typedDestination := typedOrNil(destination)
+	if typedDestination == nil {
+		return origins
+	}
+
+	for _, sr := range DefaultRegistry.selects {
+		if sr.Origin != candidateGK || sr.Destination != destinationGK {
+			continue
+		}
+		for _, candidate := range candidates {
+			typedCandidate := typedOrNil(candidate)
+			if typedCandidate == nil {
+				continue
+			}
+			accessor, err := meta.Accessor(typedCandidate)
+			if err != nil || !sr.Selects(typedCandidate, typedDestination) {
+				continue
+			}
+			origins = append(origins, HasOneDestination{
+				GroupKind: candidateGK,
+				Namespace: accessor.GetNamespace(),
+				Name:      accessor.GetName(),
+			})
+		}
+	}
+	return origins
 }
 
 // * 1:* direct relations
@@ -168,54 +292,105 @@ type HasOneDestination struct {
 //     * via selector
 //     * without selector (manually managed endpoint(s))
 //     * ExternalName with cluster local names
-//   * netpol to pods
-//   * pods to netpol
-//   * [cluster] role -> role bindings
+//   * netpol to pods (network.go)
+//   * pods to netpol (SelectsRelation)
+//   * role -> role bindings (ReferencedBy). Not cluster roles: referencedBy only searches the namespace of a
+//     namespaced object, and a cluster role's bindings could be in any namespace.
 
 // Complex one to many relationships where the results will be viewed as an advanced search page prepopulated with criteria.
 type HasManyRelations struct {
-	Origin      schema.GroupKind
-	Destination schema.GroupKind
+	Origin      schema.GroupKind `json:"origin"`
+	Destination schema.GroupKind `json:"destination"`
 	// Does this relationship exist? ie Show this relationship on Origin's page?
 	// ie a Service without selector has no known relationship to pods
-	IsApplicable func(origin runtime.Object) bool
+	IsApplicable func(origin runtime.Object) bool `json:"-"`
 	// On the search page for Destination, use these query params
-	QueryParams func(origin runtime.Object) map[string]string
-}
-
-var podNode = HasOneRelation{
-	Origin:      schema.GroupKind{Group: "", Kind: "Pod"},
-	Destination: schema.GroupKind{Group: "", Kind: "Node"},
-	IsApplicable: func(origin runtime.Object) bool {
-		pod := origin.(*corev1.Pod)
-		return pod.Spec.NodeName != ""
-	},
-	IdentifyDestination: func(origin runtime.Object) HasOneDestination {
-		pod := origin.(*corev1.Pod)
-		return HasOneDestination{
-			GroupKind: objGK(&corev1.Node{}),
-			Name:      pod.Spec.NodeName,
-		}
-	},
+	QueryParams func(origin runtime.Object) map[string]string `json:"-"`
+	// Search every namespace instead of the origin's. ie pods on a node
+	AllNamespaces bool `json:"allNamespaces"`
 }
 
-func ownerByGK(ownerReferences []metav1.OwnerReference, gk schema.GroupKind) *metav1.OwnerReference {
-	for _, or := range ownerReferences {
-		group := strings.Split(or.APIVersion, "/")
-		if len(group) > 0 && group[0] == gk.Group && or.Kind == gk.Kind {
-			return &or
+// Keys of HasManyRelations.QueryParams. Values use the kubectl --selector and --field-selector syntax.
+const (
+	LabelSelectorParam = "labelSelector"
+	FieldSelectorParam = "fieldSelector"
+)
+
+// HasManyDestination is a search for the Destination objects. An empty Namespace searches all namespaces.
+type HasManyDestination struct {
+	schema.GroupKind `json:"groupKind"`
+	Namespace        string            `json:"namespace"`
+	QueryParams      map[string]string `json:"queryParams"`
+}
+
+// registerLabelSelectorHasManyPods for an origin that selects pods with a metav1.LabelSelector. An empty selector
+// isn't applicable since it would select every pod.
+func registerLabelSelectorHasManyPods[T runtime.Object](r *Registry, origin T, selectorOf func(T) *metav1.LabelSelector) {
+	mustRegister(RegisterHasMany(r, RelationTyped[T, *corev1.Pod]{
+		Origin:      origin,
+		Destination: &corev1.Pod{},
+		IsApplicable: func(o T) bool {
+			selector, err := metav1.LabelSelectorAsSelector(selectorOf(o))
+			return err == nil && !selector.Empty()
+		},
+		ExtractParams: func(o T) map[string]string {
+			// IsApplicable already checked the error
+			selector, _ := metav1.LabelSelectorAsSelector(selectorOf(o))
+			return map[string]string{
+				LabelSelectorParam: selector.String(),
+			}
+		},
+	}))
+}
+
+// HasManyList evaluates the compiled and declared has many relations of origin. origin may be a go type or
+// unstructured. A kind with neither gets pods when it has a spec.selector.
+func HasManyList(origin Relatable, originGK schema.GroupKind) []HasManyDestination {
+	ns := ""
+	if accessor, err := meta.Accessor(origin); err == nil {
+		ns = accessor.GetNamespace()
+	}
+
+	destinations := make([]HasManyDestination, 0)
+	compiled := false
+	if typed := typedOrNil(origin); typed != nil {
+		for _, hmr := range DefaultRegistry.hasMany {
+			if hmr.Origin != originGK {
+				continue
+			}
+			compiled = true
+			if hmr.IsApplicable(typed) {
+				d := HasManyDestination{
+					GroupKind:   hmr.Destination,
+					Namespace:   ns,
+					QueryParams: hmr.QueryParams(typed),
+				}
+				if hmr.AllNamespaces {
+					d.Namespace = ""
+				}
+				destinations = append(destinations, d)
+			}
 		}
 	}
-	return nil
+
+	declared := hasDeclared(originGK, DeclaredSelector)
+	if compiled && !declared {
+		return destinations
+	}
+
+	u, err := asUnstructured(origin)
+	if err != nil {
+		return destinations
+	}
+	if declared {
+		return append(destinations, DeclaredHasManyList(u, originGK)...)
+	}
+	if d, found := specSelectorHasManyPods(u); found {
+		destinations = append(destinations, d)
+	}
+	return destinations
 }
 
 func objGK(obj runtime.Object) schema.GroupKind {
 	return obj.GetObjectKind().GroupVersionKind().GroupKind()
 }
-
-type RelationTyped[T runtime.Object, U runtime.Object] struct {
-	Origin        T
-	Destination   U
-	IsApplicable  func(T) bool
-	ExtractParams func(T) map[string]string
-}
@@ -0,0 +1,493 @@
+package relations
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestHasManyListServiceSelector(t *testing.T) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "product", Namespace: "back-end"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "product"}},
+	}
+
+	endpointSlices := HasManyDestination{
+		GroupKind:   endpointSliceGK,
+		Namespace:   "back-end",
+		QueryParams: map[string]string{LabelSelectorParam: "kubernetes.io/service-name=product"},
+	}
+
+	actual := HasManyList(svc, schema.GroupKind{Kind: "Service"})
+	expected := []HasManyDestination{{
+		GroupKind:   podGK,
+		Namespace:   "back-end",
+		QueryParams: map[string]string{LabelSelectorParam: "app=product"},
+	}, endpointSlices}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+
+	// Endpoints of a service without a selector are managed manually
+	svc.Spec.Selector = nil
+	actual = HasManyList(svc, schema.GroupKind{Kind: "Service"})
+	expected = []HasManyDestination{endpointSlices}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestHasManyListDeploymentSelector(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "product", Namespace: "back-end"},
+		Spec: appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"app": "product"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key:      "tier",
+				Operator: metav1.LabelSelectorOpIn,
+				Values:   []string{"api", "worker"},
+			}},
+		}},
+	}
+
+	actual := HasManyList(deployment, schema.GroupKind{Group: "apps", Kind: "Deployment"})
+	expected := []HasManyDestination{{
+		GroupKind:   podGK,
+		Namespace:   "back-end",
+		QueryParams: map[string]string{LabelSelectorParam: "app=product,tier in (api,worker)"},
+	}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestHasManyListNodePods(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}}
+
+	actual := HasManyList(node, schema.GroupKind{Kind: "Node"})
+	expected := []HasManyDestination{{
+		GroupKind:   podGK,
+		Namespace:   "",
+		QueryParams: map[string]string{FieldSelectorParam: "spec.nodeName=worker-1"},
+	}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestOwnerListUnstructured(t *testing.T) {
+	// A custom operator owning a pod
+	pod := &unstructured.Unstructured{}
+	pod.SetAPIVersion("v1")
+	pod.SetKind("Pod")
+	pod.SetNamespace("back-end")
+	pod.SetName("product-0")
+	isController := true
+	pod.SetOwnerReferences([]metav1.OwnerReference{
+		{APIVersion: "example.com/v1alpha1", Kind: "Backup", Name: "nightly"},
+		{APIVersion: "acme.io/v1", Kind: "ProductSet", Name: "product", Controller: &isController},
+	})
+
+	actual := OwnerList(pod)
+	expected := []HasOneDestination{
+		{GroupKind: schema.GroupKind{Group: "acme.io", Kind: "ProductSet"}, Namespace: "back-end", Name: "product", Controller: true},
+		{GroupKind: schema.GroupKind{Group: "example.com", Kind: "Backup"}, Namespace: "back-end", Name: "nightly"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestChildList(t *testing.T) {
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "product", Namespace: "back-end", UID: "d-1"}}
+
+	isController := true
+	owned := &unstructured.Unstructured{}
+	owned.SetAPIVersion("apps/v1")
+	owned.SetKind("ReplicaSet")
+	owned.SetNamespace("back-end")
+	owned.SetName("product-5d8f")
+	owned.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "product", UID: "d-1", Controller: &isController}})
+
+	// Same name, but a previous incarnation of the deployment
+	orphan := owned.DeepCopy()
+	orphan.SetName("product-77aa")
+	orphan.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "product", UID: "d-0"}})
+
+	actual := ChildList(deployment, []runtime.Object{owned, orphan})
+	expected := []HasOneDestination{
+		{GroupKind: replicaSetGK, Namespace: "back-end", Name: "product-5d8f", Controller: true},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestRelationsListIngressBackends(t *testing.T) {
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "front-end"},
+		Spec: networkingv1.IngressSpec{
+			DefaultBackend: &networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+				Name: "not-found", Port: networkingv1.ServiceBackendPort{Number: 8080},
+			}},
+			Rules: []networkingv1.IngressRule{{
+				Host: "shop.example.com",
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path: "/api",
+						Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+							Name: "product", Port: networkingv1.ServiceBackendPort{Name: "http"},
+						}},
+					}},
+				}},
+			}},
+		},
+	}
+
+	actual := RelationsList(ingress, schema.GroupKind{Group: "networking.k8s.io", Kind: "Ingress"})
+	expected := []HasOneDestination{
+		{GroupKind: serviceGK, Namespace: "front-end", Name: "not-found", Label: "default backend -> 8080"},
+		{GroupKind: serviceGK, Namespace: "front-end", Name: "product", Label: "shop.example.com/api -> http"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestReferencedByConfigMap(t *testing.T) {
+	mounts := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "product-0", Namespace: "back-end"},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{{
+				Name:         "config",
+				VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "product"}}},
+			}},
+			Containers: []corev1.Container{{
+				Name: "app",
+				Env: []corev1.EnvVar{{
+					Name: "LOG_LEVEL",
+					ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "product"}, Key: "logLevel",
+					}},
+				}},
+			}},
+		},
+	}
+	unrelated := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "cart-0", Namespace: "back-end"}}
+
+	actual := ReferencedBy(
+		HasOneDestination{GroupKind: configMapGK, Namespace: "back-end", Name: "product"},
+		podGK,
+		[]Relatable{mounts, unrelated},
+	)
+	expected := []HasOneDestination{
+		{GroupKind: podGK, Namespace: "back-end", Name: "product-0", Label: "volume config, env LOG_LEVEL in app"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestScalingRelations(t *testing.T) {
+	hpa := &autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "product", Namespace: "back-end"},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "product"},
+		},
+	}
+	actual := RelationsList(hpa, schema.GroupKind{Group: "autoscaling", Kind: "HorizontalPodAutoscaler"})
+	expected := []HasOneDestination{{GroupKind: deploymentGK, Namespace: "back-end", Name: "product", Label: "scale target"}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "product", Namespace: "back-end"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "product"}},
+		}},
+	}
+	covering := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "product", Namespace: "back-end"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "product"}}},
+	}
+	other := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "cart", Namespace: "back-end"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "cart"}}},
+	}
+	pdbGK := schema.GroupKind{Group: "policy", Kind: "PodDisruptionBudget"}
+	// Neither an empty nor a null selector is applicable, the same as the PDB's pods relation
+	everything := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "everything", Namespace: "back-end"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{}},
+	}
+	nothing := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "nothing", Namespace: "back-end"},
+	}
+	otherNamespace := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "everything", Namespace: "front-end"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{}},
+	}
+	actual = SelectedBy(deployment, deploymentGK, pdbGK, []Relatable{covering, other, everything, nothing, otherNamespace})
+	expected = []HasOneDestination{
+		{GroupKind: pdbGK, Namespace: "back-end", Name: "product"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestDeclaredRelations(t *testing.T) {
+	declared, err := ParseDeclaredRelations([]byte(`
+relations:
+- origin: {group: cert-manager.io, kind: Certificate}
+  destination: {kind: Secret}
+  field: spec.secretName
+- origin: {group: cert-manager.io, kind: Certificate}
+  destination: {group: cert-manager.io, kind: ClusterIssuer}
+  field: spec.issuerRef.name
+  clusterScoped: true
+  label: issuer
+- origin: {group: example.com, kind: Backup}
+  destination: {kind: Secret}
+  field: spec.targets[].credentials.name
+- origin: {group: example.com, kind: Backup}
+  destination: {kind: ConfigMap}
+  field: spec.refs[].name
+  namespaceField: spec.refs[].namespace
+- origin: {group: example.com, kind: Backup}
+  destination: {kind: Pod}
+  type: selector
+  field: spec.selector
+`))
+	if err != nil {
+		t.Fatalf("unable to parse: %v", err)
+	}
+	RegisterDeclaredRelations(declared)
+	defer func() { declaredRelations = make([]DeclaredRelation, 0) }()
+
+	certificate := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "Certificate",
+		"metadata":   map[string]interface{}{"name": "shop", "namespace": "front-end"},
+		"spec": map[string]interface{}{
+			"secretName": "shop-tls",
+			"issuerRef":  map[string]interface{}{"name": "letsencrypt", "kind": "ClusterIssuer"},
+		},
+	}}
+	actual := DeclaredRelationsList(certificate, schema.GroupKind{Group: "cert-manager.io", Kind: "Certificate"})
+	expected := []HasOneDestination{
+		{GroupKind: secretGK, Namespace: "front-end", Name: "shop-tls", Label: "spec.secretName"},
+		{GroupKind: schema.GroupKind{Group: "cert-manager.io", Kind: "ClusterIssuer"}, Name: "letsencrypt", Label: "issuer"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+
+	backupGK := schema.GroupKind{Group: "example.com", Kind: "Backup"}
+	backup := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "nightly", "namespace": "back-end"},
+		"spec": map[string]interface{}{
+			"targets": []interface{}{
+				map[string]interface{}{"credentials": map[string]interface{}{"name": "s3"}},
+				map[string]interface{}{"credentials": map[string]interface{}{"name": "gcs"}},
+			},
+			// The first ref is in the backup's namespace
+			"refs": []interface{}{
+				map[string]interface{}{"name": "a"},
+				map[string]interface{}{"name": "b", "namespace": "other"},
+			},
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "product"}},
+		},
+	}}
+	actual = DeclaredRelationsList(backup, backupGK)
+	configMapGK := schema.GroupKind{Kind: "ConfigMap"}
+	expected = []HasOneDestination{
+		{GroupKind: secretGK, Namespace: "back-end", Name: "s3", Label: "spec.targets[].credentials.name"},
+		{GroupKind: secretGK, Namespace: "back-end", Name: "gcs", Label: "spec.targets[].credentials.name"},
+		{GroupKind: configMapGK, Namespace: "back-end", Name: "a", Label: "spec.refs[].name"},
+		{GroupKind: configMapGK, Namespace: "other", Name: "b", Label: "spec.refs[].name"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+
+	actualHasMany := DeclaredHasManyList(backup, backupGK)
+	expectedHasMany := []HasManyDestination{{
+		GroupKind:   podGK,
+		Namespace:   "back-end",
+		QueryParams: map[string]string{LabelSelectorParam: "app=product"},
+	}}
+	if !reflect.DeepEqual(actualHasMany, expectedHasMany) {
+		t.Errorf("expected %+v, got %+v", expectedHasMany, actualHasMany)
+	}
+}
+
+func TestRelationsListUnstructured(t *testing.T) {
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "product-0", "namespace": "back-end"},
+		"spec":       map[string]interface{}{"nodeName": "worker-1"},
+	}}
+	actual := RelationsList(pod, podGK)
+	expected := []HasOneDestination{{GroupKind: schema.GroupKind{Kind: "Node"}, Name: "worker-1"}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+
+	// A custom resource without compiled or declared relations selects the pods of its template at spec.selector
+	rollout := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Rollout",
+		"metadata":   map[string]interface{}{"name": "product", "namespace": "back-end"},
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "product"}},
+			"template": map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "product"}}},
+		},
+	}}
+	actualHasMany := HasManyList(rollout, schema.GroupKind{Group: "argoproj.io", Kind: "Rollout"})
+	expectedHasMany := []HasManyDestination{{
+		GroupKind:   podGK,
+		Namespace:   "back-end",
+		QueryParams: map[string]string{LabelSelectorParam: "app=product"},
+	}}
+	if !reflect.DeepEqual(actualHasMany, expectedHasMany) {
+		t.Errorf("expected %+v, got %+v", expectedHasMany, actualHasMany)
+	}
+
+	// Without a template the selector is for something else
+	serviceMonitor := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "monitoring.coreos.com/v1",
+		"kind":       "ServiceMonitor",
+		"metadata":   map[string]interface{}{"name": "product", "namespace": "back-end"},
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "product"}},
+		},
+	}}
+	if actual := HasManyList(serviceMonitor, schema.GroupKind{Group: "monitoring.coreos.com", Kind: "ServiceMonitor"}); len(actual) != 0 {
+		t.Errorf("expected no pods, got %+v", actual)
+	}
+}
+
+func TestTypedOtherVersion(t *testing.T) {
+	pdb := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "policy/v1beta1",
+		"kind":       "PodDisruptionBudget",
+		"metadata":   map[string]interface{}{"name": "product", "namespace": "back-end"},
+	}}
+	if _, err := Typed(pdb); err == nil || !strings.Contains(err.Error(), "policy/v1beta1") {
+		t.Errorf("expected an error for an uncompiled version, got %v", err)
+	}
+
+	rollout := &unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "argoproj.io/v1alpha1", "kind": "Rollout"}}
+	if typed, err := Typed(rollout); err != nil || typed != rollout {
+		t.Errorf("expected an unknown kind as is, got %v %v", typed, err)
+	}
+}
+
+func TestGraphSortLabels(t *testing.T) {
+	ingress := HasOneDestination{GroupKind: schema.GroupKind{Group: "networking.k8s.io", Kind: "Ingress"}, Namespace: "front-end", Name: "shop"}
+	svc := HasOneDestination{GroupKind: schema.GroupKind{Kind: "Service"}, Namespace: "front-end", Name: "shop"}
+
+	graph := NewGraph()
+	for _, label := range []string{"shop.example.com/cart", "shop.example.com/", "shop.example.com/api"} {
+		graph.AddEdge(ingress, svc, EdgeReference, label)
+	}
+	graph.Sort()
+
+	labels := []string{graph.Edges[0].Label, graph.Edges[1].Label, graph.Edges[2].Label}
+	expected := []string{"shop.example.com/", "shop.example.com/api", "shop.example.com/cart"}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected %v, got %v", expected, labels)
+	}
+}
+
+func TestGraphExport(t *testing.T) {
+	rs := HasOneDestination{GroupKind: replicaSetGK, Namespace: "back-end", Name: "product-5d8f"}
+	deployment := HasOneDestination{GroupKind: deploymentGK, Namespace: "back-end", Name: "product"}
+
+	graph := NewGraph()
+	graph.AddEdge(rs, deployment, EdgeOwner, "")
+	graph.AddEdge(rs, deployment, EdgeOwner, "")
+	graph.Sort()
+
+	if len(graph.Nodes) != 2 || len(graph.Edges) != 1 {
+		t.Fatalf("expected 2 nodes and 1 edge, got %+v", graph)
+	}
+
+	expectedDOT := `digraph relations {
+  node [shape=box];
+  "Deployment.apps/back-end/product" [label="Deployment/product"];
+  "ReplicaSet.apps/back-end/product-5d8f" [label="ReplicaSet/product-5d8f"];
+  "ReplicaSet.apps/back-end/product-5d8f" -> "Deployment.apps/back-end/product" [label="owner", style=bold];
+}
+`
+	if actual := graph.DOT(); actual != expectedDOT {
+		t.Errorf("expected %s, got %s", expectedDOT, actual)
+	}
+
+	expectedMermaid := `graph LR
+  n0["Deployment/product"]
+  n1["ReplicaSet/product-5d8f"]
+  n1 -->|"owner"| n0
+`
+	if actual := graph.Mermaid(); actual != expectedMermaid {
+		t.Errorf("expected %s, got %s", expectedMermaid, actual)
+	}
+}
+
+func TestRegistryValidatesAndIsTypeSafe(t *testing.T) {
+	r := NewRegistry(runtime.NewScheme())
+	corev1.AddToScheme(r.scheme)
+
+	err := RegisterHasOne(r, RelationTyped[*corev1.Pod, *corev1.Node]{Origin: &corev1.Pod{}, Destination: &corev1.Node{}})
+	if err == nil {
+		t.Errorf("expected an error without IdentifyDestination")
+	}
+
+	// apps isn't in the scheme
+	err = RegisterHasMany(r, RelationTyped[*appsv1.Deployment, *corev1.Pod]{
+		Origin:        &appsv1.Deployment{},
+		Destination:   &corev1.Pod{},
+		ExtractParams: func(*appsv1.Deployment) map[string]string { return nil },
+	})
+	if err == nil {
+		t.Errorf("expected an error for a type missing from the scheme")
+	}
+
+	err = RegisterHasOne(r, RelationTyped[*corev1.Pod, *corev1.ServiceAccount]{
+		Origin:      &corev1.Pod{},
+		Destination: &corev1.ServiceAccount{},
+		IdentifyDestination: func(pod *corev1.Pod) HasOneDestination {
+			return HasOneDestination{Namespace: pod.Namespace, Name: pod.Spec.ServiceAccountName}
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to register: %v", err)
+	}
+
+	hor := r.hasOne[0]
+	if hor.Origin != podGK || hor.Destination != (schema.GroupKind{Kind: "ServiceAccount"}) {
+		t.Errorf("unexpected kinds %+v -> %+v", hor.Origin, hor.Destination)
+	}
+	// A wrong type isn't applicable instead of panicking
+	if hor.IsApplicable(&corev1.Node{}) {
+		t.Errorf("expected a Node not to be applicable to a Pod relation")
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "back-end"}, Spec: corev1.PodSpec{ServiceAccountName: "product"}}
+	expected := HasOneDestination{GroupKind: schema.GroupKind{Kind: "ServiceAccount"}, Namespace: "back-end", Name: "product"}
+	if actual := hor.IdentifyDestination(pod); actual != expected {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
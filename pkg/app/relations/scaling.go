@@ -0,0 +1,95 @@
+package relations
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// HorizontalPodAutoscaler -> the workload it scales. PodDisruptionBudget -> the pods, and so the workloads, its
+// selector matches.
+
+func registerScalingRelations(r *Registry) {
+	// The cluster's preferred HPA version decides which go type GetResource converts to, so register both
+	v1Ref := func(hpa *autoscalingv1.HorizontalPodAutoscaler) (string, string, string) {
+		return hpa.Spec.ScaleTargetRef.APIVersion, hpa.Spec.ScaleTargetRef.Kind, hpa.Spec.ScaleTargetRef.Name
+	}
+	v2Ref := func(hpa *autoscalingv2.HorizontalPodAutoscaler) (string, string, string) {
+		return hpa.Spec.ScaleTargetRef.APIVersion, hpa.Spec.ScaleTargetRef.Kind, hpa.Spec.ScaleTargetRef.Name
+	}
+	registerScaleTarget(r, &autoscalingv1.HorizontalPodAutoscaler{}, &appsv1.Deployment{}, deploymentGK, v1Ref)
+	registerScaleTarget(r, &autoscalingv1.HorizontalPodAutoscaler{}, &appsv1.StatefulSet{}, statefulSetGK, v1Ref)
+	registerScaleTarget(r, &autoscalingv1.HorizontalPodAutoscaler{}, &appsv1.ReplicaSet{}, replicaSetGK, v1Ref)
+	registerScaleTarget(r, &autoscalingv2.HorizontalPodAutoscaler{}, &appsv1.Deployment{}, deploymentGK, v2Ref)
+	registerScaleTarget(r, &autoscalingv2.HorizontalPodAutoscaler{}, &appsv1.StatefulSet{}, statefulSetGK, v2Ref)
+	registerScaleTarget(r, &autoscalingv2.HorizontalPodAutoscaler{}, &appsv1.ReplicaSet{}, replicaSetGK, v2Ref)
+
+	registerLabelSelectorHasManyPods(r, &policyv1.PodDisruptionBudget{}, func(pdb *policyv1.PodDisruptionBudget) *metav1.LabelSelector {
+		return pdb.Spec.Selector
+	})
+
+	// A workload is covered by a PDB when the PDB would select the pods of its template
+	registerPDBSelects(r, &corev1.Pod{}, func(pod *corev1.Pod) (string, map[string]string) {
+		return pod.Namespace, pod.Labels
+	})
+	registerPDBSelects(r, &appsv1.Deployment{}, func(d *appsv1.Deployment) (string, map[string]string) {
+		return d.Namespace, d.Spec.Template.Labels
+	})
+	registerPDBSelects(r, &appsv1.StatefulSet{}, func(sts *appsv1.StatefulSet) (string, map[string]string) {
+		return sts.Namespace, sts.Spec.Template.Labels
+	})
+}
+
+// registerScaleTarget for one HPA version and one kind of scale target. refOf returns the apiVersion, kind and name
+// of the HPA's scaleTargetRef.
+func registerScaleTarget[H runtime.Object, U runtime.Object](r *Registry, hpa H, target U, targetGK schema.GroupKind, refOf func(H) (string, string, string)) {
+	mustRegister(RegisterHasOne(r, RelationTyped[H, U]{
+		Origin:      hpa,
+		Destination: target,
+		IsApplicable: func(h H) bool {
+			apiVersion, kind, _ := refOf(h)
+			gv, err := schema.ParseGroupVersion(apiVersion)
+			return err == nil && gv.WithKind(kind).GroupKind() == targetGK
+		},
+		IdentifyDestination: func(h H) HasOneDestination {
+			_, _, name := refOf(h)
+			// An HPA scales a target in its own namespace
+			ns := ""
+			if accessor, err := meta.Accessor(h); err == nil {
+				ns = accessor.GetNamespace()
+			}
+			return HasOneDestination{
+				Namespace: ns,
+				Name:      name,
+				Label:     "scale target",
+			}
+		},
+	}))
+}
+
+func registerPDBSelects[U runtime.Object](r *Registry, destination U, podLabels func(U) (string, map[string]string)) {
+	mustRegister(RegisterSelects(r, RelationTyped[*policyv1.PodDisruptionBudget, U]{
+		Origin:      &policyv1.PodDisruptionBudget{},
+		Destination: destination,
+		// The same check as the PDB's pods relation, registerLabelSelectorHasManyPods, so both skip an empty selector
+		IsApplicable: func(pdb *policyv1.PodDisruptionBudget) bool {
+			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			return err == nil && !selector.Empty()
+		},
+		Selects: func(pdb *policyv1.PodDisruptionBudget, d U) bool {
+			ns, l := podLabels(d)
+			if pdb.Namespace != ns {
+				return false
+			}
+			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			return err == nil && selector.Matches(labels.Set(l))
+		},
+	}))
+}
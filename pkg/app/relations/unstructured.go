@@ -0,0 +1,98 @@
+package relations
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// Objects from the dynamic client are unstructured. Kinds with compiled relations are converted to their go type, the
+// fast path, and every other kind gets the relations that only need metadata or field paths: owners, declared
+// relations and the spec.selector of a workload.
+
+// Typed converts an unstructured origin to its go type when it has compiled relations. Every other origin, including
+// unstructured objects of unknown kinds, is returned as is. It's an error when the kind has compiled relations for
+// other versions only, ie a policy/v1beta1 PodDisruptionBudget. The go types of different versions can't be converted
+// without the api server's internal types, so the object gets none of the kind's compiled relations.
+func Typed(origin Relatable) (Relatable, error) {
+	u, ok := origin.(*unstructured.Unstructured)
+	if !ok {
+		return origin, nil
+	}
+
+	gvk := u.GroupVersionKind()
+	if !DefaultRegistry.scheme.Recognizes(gvk) {
+		if versions := DefaultRegistry.scheme.VersionsForGroupKind(gvk.GroupKind()); len(versions) > 0 {
+			return nil, fmt.Errorf("relations of %s %s are only available for %v, not %s", gvk.Kind, u.GetName(), versions, gvk.GroupVersion())
+		}
+		return origin, nil
+	}
+
+	typed, err := DefaultRegistry.scheme.New(gvk)
+	if err != nil {
+		return nil, fmt.Errorf("unable to instantiate %s for %s: %w", gvk, u.GetName(), err)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), typed); err != nil {
+		return nil, fmt.Errorf("unable to convert %s to %s: %w", u.GetName(), gvk, err)
+	}
+	return typed, nil
+}
+
+// typedOrNil is Typed for the relations that need a go type. nil when there isn't one.
+func typedOrNil(origin Relatable) Relatable {
+	typed, err := Typed(origin)
+	if err != nil {
+		return nil
+	}
+	if _, stillUnstructured := typed.(*unstructured.Unstructured); stillUnstructured {
+		return nil
+	}
+	return typed
+}
+
+// asUnstructured for the relations evaluated on field paths.
+func asUnstructured(origin Relatable) (*unstructured.Unstructured, error) {
+	if u, ok := origin.(*unstructured.Unstructured); ok {
+		return u, nil
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(origin)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert %T to unstructured: %w", origin, err)
+	}
+	return &unstructured.Unstructured{Object: content}, nil
+}
+
+// specSelectorHasManyPods is the selector relation of a kind without a compiled or declared one. Most workload-like
+// custom resources, ie an Argo Rollout, select their pods with a metav1.LabelSelector at spec.selector. The kind must
+// also have a pod spec.template. Plenty of kinds select something else, ie a ServiceMonitor selects services.
+func specSelectorHasManyPods(origin *unstructured.Unstructured) (HasManyDestination, bool) {
+	if _, found, err := unstructured.NestedMap(origin.Object, "spec", "template"); err != nil || !found {
+		return HasManyDestination{}, false
+	}
+	content, found, err := unstructured.NestedMap(origin.Object, "spec", "selector")
+	if err != nil || !found {
+		return HasManyDestination{}, false
+	}
+	_, hasMatchLabels := content["matchLabels"]
+	_, hasMatchExpressions := content["matchExpressions"]
+	if !hasMatchLabels && !hasMatchExpressions {
+		return HasManyDestination{}, false
+	}
+
+	ls := &metav1.LabelSelector{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, ls); err != nil {
+		return HasManyDestination{}, false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(ls)
+	if err != nil || selector.Empty() {
+		return HasManyDestination{}, false
+	}
+
+	return HasManyDestination{
+		GroupKind:   podGK,
+		Namespace:   origin.GetNamespace(),
+		QueryParams: map[string]string{LabelSelectorParam: selector.String()},
+	}, true
+}
@@ -0,0 +1,208 @@
+package relations
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Storage: Pod -> PersistentVolumeClaim -> PersistentVolume -> StorageClass
+// Config: Pod -> ConfigMap, Secret, ServiceAccount, PriorityClass
+
+func registerVolumeRelations(r *Registry) {
+	mustRegister(RegisterHasOne(r, RelationTyped[*corev1.Pod, *corev1.ServiceAccount]{
+		Origin:      &corev1.Pod{},
+		Destination: &corev1.ServiceAccount{},
+		IsApplicable: func(pod *corev1.Pod) bool {
+			return pod.Spec.ServiceAccountName != ""
+		},
+		IdentifyDestination: func(pod *corev1.Pod) HasOneDestination {
+			return HasOneDestination{Namespace: pod.Namespace, Name: pod.Spec.ServiceAccountName}
+		},
+	}))
+
+	mustRegister(RegisterHasOne(r, RelationTyped[*corev1.Pod, *schedulingv1.PriorityClass]{
+		Origin:      &corev1.Pod{},
+		Destination: &schedulingv1.PriorityClass{},
+		IsApplicable: func(pod *corev1.Pod) bool {
+			return pod.Spec.PriorityClassName != ""
+		},
+		IdentifyDestination: func(pod *corev1.Pod) HasOneDestination {
+			return HasOneDestination{Name: pod.Spec.PriorityClassName}
+		},
+	}))
+
+	mustRegister(RegisterHasOne(r, RelationTyped[*corev1.PersistentVolumeClaim, *corev1.PersistentVolume]{
+		Origin:      &corev1.PersistentVolumeClaim{},
+		Destination: &corev1.PersistentVolume{},
+		IsApplicable: func(pvc *corev1.PersistentVolumeClaim) bool {
+			return pvc.Spec.VolumeName != ""
+		},
+		IdentifyDestination: func(pvc *corev1.PersistentVolumeClaim) HasOneDestination {
+			return HasOneDestination{Name: pvc.Spec.VolumeName}
+		},
+	}))
+
+	mustRegister(RegisterHasOne(r, RelationTyped[*corev1.PersistentVolumeClaim, *storagev1.StorageClass]{
+		Origin:      &corev1.PersistentVolumeClaim{},
+		Destination: &storagev1.StorageClass{},
+		IsApplicable: func(pvc *corev1.PersistentVolumeClaim) bool {
+			return pvc.Spec.StorageClassName != nil && *pvc.Spec.StorageClassName != ""
+		},
+		IdentifyDestination: func(pvc *corev1.PersistentVolumeClaim) HasOneDestination {
+			return HasOneDestination{Name: *pvc.Spec.StorageClassName}
+		},
+	}))
+
+	mustRegister(RegisterHasOne(r, RelationTyped[*corev1.PersistentVolume, *storagev1.StorageClass]{
+		Origin:      &corev1.PersistentVolume{},
+		Destination: &storagev1.StorageClass{},
+		IsApplicable: func(pv *corev1.PersistentVolume) bool {
+			return pv.Spec.StorageClassName != ""
+		},
+		IdentifyDestination: func(pv *corev1.PersistentVolume) HasOneDestination {
+			return HasOneDestination{Name: pv.Spec.StorageClassName}
+		},
+	}))
+
+	mustRegister(RegisterHasOne(r, RelationTyped[*corev1.PersistentVolume, *corev1.PersistentVolumeClaim]{
+		Origin:      &corev1.PersistentVolume{},
+		Destination: &corev1.PersistentVolumeClaim{},
+		IsApplicable: func(pv *corev1.PersistentVolume) bool {
+			return pv.Spec.ClaimRef != nil
+		},
+		IdentifyDestination: func(pv *corev1.PersistentVolume) HasOneDestination {
+			return HasOneDestination{Namespace: pv.Spec.ClaimRef.Namespace, Name: pv.Spec.ClaimRef.Name}
+		},
+	}))
+
+	registerPodReferences(r, &corev1.PersistentVolumeClaim{}, pvcGK)
+	registerPodReferences(r, &corev1.ConfigMap{}, configMapGK)
+	registerPodReferences(r, &corev1.Secret{}, secretGK)
+}
+
+func registerPodReferences[U runtime.Object](r *Registry, destination U, destinationGK schema.GroupKind) {
+	mustRegister(RegisterReferences(r, RelationTyped[*corev1.Pod, U]{
+		Origin:      &corev1.Pod{},
+		Destination: destination,
+		IdentifyDestinations: func(pod *corev1.Pod) []HasOneDestination {
+			return podReferencesByName(pod)[destinationGK].destinations(destinationGK, pod.Namespace)
+		},
+	}))
+}
+
+// namedReferences collects, per destination name, how the pod uses it. ie "volume config, env LOG_LEVEL"
+type namedReferences struct {
+	names  []string
+	labels map[string][]string
+	// Used at least once without optional: true
+	required map[string]bool
+}
+
+func newNamedReferences() *namedReferences {
+	return &namedReferences{labels: make(map[string][]string), required: make(map[string]bool)}
+}
+
+func (nr *namedReferences) add(name string, label string, optional *bool) {
+	if _, found := nr.labels[name]; !found {
+		nr.names = append(nr.names, name)
+	}
+	nr.labels[name] = append(nr.labels[name], label)
+	if optional == nil || !*optional {
+		nr.required[name] = true
+	}
+}
+
+func (nr *namedReferences) destinations(gk schema.GroupKind, ns string) []HasOneDestination {
+	destinations := make([]HasOneDestination, 0)
+	if nr == nil {
+		return destinations
+	}
+	for _, name := range nr.names {
+		destinations = append(destinations, HasOneDestination{
+			GroupKind: gk,
+			Namespace: ns,
+			Name:      name,
+			Label:     strings.Join(nr.labels[name], ", "),
+			Optional:  !nr.required[name],
+		})
+	}
+	return destinations
+}
+
+// podReferencesByName finds every PersistentVolumeClaim, ConfigMap and Secret named in the pod's volumes, env,
+// envFrom and imagePullSecrets. A destination is optional when every use of it is.
+func podReferencesByName(pod *corev1.Pod) map[schema.GroupKind]*namedReferences {
+	refs := map[schema.GroupKind]*namedReferences{
+		pvcGK:       newNamedReferences(),
+		configMapGK: newNamedReferences(),
+		secretGK:    newNamedReferences(),
+	}
+
+	for _, v := range pod.Spec.Volumes {
+		switch {
+		case v.PersistentVolumeClaim != nil:
+			refs[pvcGK].add(v.PersistentVolumeClaim.ClaimName, fmt.Sprintf("volume %s", v.Name), nil)
+		case v.ConfigMap != nil:
+			refs[configMapGK].add(v.ConfigMap.Name, fmt.Sprintf("volume %s", v.Name), v.ConfigMap.Optional)
+		case v.Secret != nil:
+			refs[secretGK].add(v.Secret.SecretName, fmt.Sprintf("volume %s", v.Name), v.Secret.Optional)
+		case v.Projected != nil:
+			for _, source := range v.Projected.Sources {
+				if source.ConfigMap != nil {
+					refs[configMapGK].add(source.ConfigMap.Name, fmt.Sprintf("volume %s", v.Name), source.ConfigMap.Optional)
+				}
+				if source.Secret != nil {
+					refs[secretGK].add(source.Secret.Name, fmt.Sprintf("volume %s", v.Name), source.Secret.Optional)
+				}
+			}
+		}
+	}
+
+	for _, c := range podContainers(pod) {
+		for _, envFrom := range c.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				refs[configMapGK].add(envFrom.ConfigMapRef.Name, fmt.Sprintf("envFrom in %s", c.Name), envFrom.ConfigMapRef.Optional)
+			}
+			if envFrom.SecretRef != nil {
+				refs[secretGK].add(envFrom.SecretRef.Name, fmt.Sprintf("envFrom in %s", c.Name), envFrom.SecretRef.Optional)
+			}
+		}
+		for _, env := range c.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if env.ValueFrom.ConfigMapKeyRef != nil {
+				refs[configMapGK].add(env.ValueFrom.ConfigMapKeyRef.Name, fmt.Sprintf("env %s in %s", env.Name, c.Name), env.ValueFrom.ConfigMapKeyRef.Optional)
+			}
+			if env.ValueFrom.SecretKeyRef != nil {
+				refs[secretGK].add(env.ValueFrom.SecretKeyRef.Name, fmt.Sprintf("env %s in %s", env.Name, c.Name), env.ValueFrom.SecretKeyRef.Optional)
+			}
+		}
+	}
+
+	for _, ips := range pod.Spec.ImagePullSecrets {
+		refs[secretGK].add(ips.Name, "imagePullSecret", nil)
+	}
+
+	return refs
+}
+
+// podContainers returns the name, env and envFrom of init, app and ephemeral containers.
+func podContainers(pod *corev1.Pod) []corev1.Container {
+	containers := append([]corev1.Container{}, pod.Spec.InitContainers...)
+	containers = append(containers, pod.Spec.Containers...)
+	for _, ec := range pod.Spec.EphemeralContainers {
+		containers = append(containers, corev1.Container{
+			Name:    ec.Name,
+			Env:     ec.Env,
+			EnvFrom: ec.EnvFrom,
+		})
+	}
+	return containers
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/cheriot/kubenav/pkg/app/relations"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var scalingGKs = []schema.GroupKind{
+	{Group: autoscalingv2.GroupName, Kind: "HorizontalPodAutoscaler"},
+	{Group: policyv1.GroupName, Kind: "PodDisruptionBudget"},
+}
+
+// ScalingObject is an HPA or PDB of a workload with its row from the table printers. ie the MINPODS, MAXPODS and
+// REPLICAS of an HPA or the ALLOWED DISRUPTIONS of a PDB.
+type ScalingObject struct {
+	relations.HasOneDestination `json:"object"`
+	Columns                     []string      `json:"columns"`
+	Cells                       []interface{} `json:"cells"`
+	ErrorMsg                    string        `json:"error"`
+}
+
+// findScaling prints the HPAs and PDBs among the objects that reference a workload. listed has the objects
+// findReferencedBy found them in.
+func (kc *KubeCluster) findScaling(referencedBy []relations.HasOneDestination, listed relatableCache) []ScalingObject {
+	scaling := make([]ScalingObject, 0)
+	for _, d := range referencedBy {
+		if !isScalingGK(d.GroupKind) {
+			continue
+		}
+
+		so := ScalingObject{
+			HasOneDestination: d,
+			Columns:           make([]string, 0),
+			Cells:             make([]interface{}, 0),
+		}
+		scaling = append(scaling, so)
+		last := &scaling[len(scaling)-1]
+
+		apiResource, found := findAPIResourceByGK(kc.apiResources, d.GroupKind)
+		if !found {
+			last.ErrorMsg = fmt.Sprintf("unable to find an api resource for %s", d.GroupKind)
+			continue
+		}
+		obj, found := findListed(listed[d.GroupKind], d)
+		if !found {
+			last.ErrorMsg = fmt.Sprintf("unable to find %s %s among the listed %s", d.Kind, d.Name, d.GroupKind)
+			continue
+		}
+		table, err := PrintObject(kc.scheme, apiResource, obj)
+		if err != nil {
+			last.ErrorMsg = err.Error()
+			continue
+		}
+		if len(table.Rows) > 0 {
+			for _, cd := range table.ColumnDefinitions {
+				last.Columns = append(last.Columns, cd.Name)
+			}
+			last.Cells = table.Rows[0].Cells
+		}
+	}
+	return scaling
+}
+
+func findListed(objs []relations.Relatable, d relations.HasOneDestination) (*unstructured.Unstructured, bool) {
+	for _, obj := range objs {
+		u, ok := obj.(*unstructured.Unstructured)
+		if ok && u.GetNamespace() == d.Namespace && u.GetName() == d.Name {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+func isScalingGK(gk schema.GroupKind) bool {
+	for _, s := range scalingGKs {
+		if s == gk {
+			return true
+		}
+	}
+	return false
+}
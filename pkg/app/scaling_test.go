@@ -0,0 +1,50 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/cheriot/kubenav/pkg/app/relations"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestFindScalingPrintsListedObjects(t *testing.T) {
+	pdbResource := metav1.APIResource{Name: "poddisruptionbudgets", Namespaced: true, Group: "policy", Version: "v1", Kind: "PodDisruptionBudget"}
+	scheme := runtime.NewScheme()
+	if err := schemeBuilder.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	// No dynamic client, so getting the PDB again would panic
+	kc := &KubeCluster{name: "test", apiResources: []metav1.APIResource{pdbResource}, scheme: scheme}
+
+	minAvailable := intstr.FromInt(1)
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&policyv1.PodDisruptionBudget{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "policy/v1", Kind: "PodDisruptionBudget"},
+		ObjectMeta: metav1.ObjectMeta{Name: "product", Namespace: "back-end"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{MinAvailable: &minAvailable, Selector: &metav1.LabelSelector{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdbGK := toGK(pdbResource)
+	listed := relatableCache{pdbGK: {&unstructured.Unstructured{Object: content}}}
+
+	referencedBy := []relations.HasOneDestination{
+		{GroupKind: pdbGK, Namespace: "back-end", Name: "product"},
+		{GroupKind: pdbGK, Namespace: "back-end", Name: "deleted"},
+	}
+	scaling := kc.findScaling(referencedBy, listed)
+	if len(scaling) != 2 {
+		t.Fatalf("expected 2 scaling objects, got %+v", scaling)
+	}
+	if scaling[0].ErrorMsg != "" || len(scaling[0].Cells) == 0 || len(scaling[0].Columns) != len(scaling[0].Cells) {
+		t.Errorf("expected the listed pdb's row, got %+v", scaling[0])
+	}
+	if scaling[1].ErrorMsg == "" {
+		t.Errorf("expected an error for a pdb that wasn't listed, got %+v", scaling[1])
+	}
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/cheriot/kubenav/pkg/app/relations"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	discoveryv1client "k8s.io/client-go/kubernetes/typed/discovery/v1"
+	networkingv1client "k8s.io/client-go/kubernetes/typed/networking/v1"
+)
+
+type TrafficPath struct {
+	Namespace string           `json:"namespace"`
+	Ingress   string           `json:"ingress"`
+	Backends  []TrafficBackend `json:"backends"`
+}
+
+// TrafficBackend follows one ingress route through its Service to the pods in the Service's EndpointSlices.
+type TrafficBackend struct {
+	relations.IngressServiceBackend `json:"backend"`
+	Label                           string       `json:"label"`
+	Pods                            []TrafficPod `json:"pods"`
+	// ie the Service doesn't exist or has no such port
+	ErrorMsg string `json:"error"`
+}
+
+type TrafficPod struct {
+	Name      string   `json:"name"`
+	Ready     bool     `json:"ready"`
+	NodeName  string   `json:"nodeName"`
+	Addresses []string `json:"addresses"`
+}
+
+// TrafficPath shows, for each route of an ingress, which pods receive its requests and which of those aren't ready.
+func (kc *KubeCluster) TrafficPath(ctx context.Context, nsName string, ingressName string) (*TrafficPath, error) {
+	networkingclient, err := networkingv1client.NewForConfig(kc.restClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create networkingclient for %s: %w", kc.name, err)
+	}
+	coreclient, err := corev1client.NewForConfig(kc.restClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create coreclient for %s: %w", kc.name, err)
+	}
+	discoveryclient, err := discoveryv1client.NewForConfig(kc.restClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create discoveryclient for %s: %w", kc.name, err)
+	}
+
+	ingress, err := networkingclient.Ingresses(nsName).Get(ctx, ingressName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get ingress %s/%s: %w", nsName, ingressName, err)
+	}
+
+	path := &TrafficPath{
+		Namespace: nsName,
+		Ingress:   ingressName,
+		Backends:  make([]TrafficBackend, 0),
+	}
+	for _, backend := range relations.IngressServiceBackends(ingress) {
+		tb := TrafficBackend{
+			IngressServiceBackend: backend,
+			Label:                 backend.Label(),
+			Pods:                  make([]TrafficPod, 0),
+		}
+
+		svc, err := coreclient.Services(nsName).Get(ctx, backend.Service.Name, metav1.GetOptions{})
+		if err != nil {
+			tb.ErrorMsg = fmt.Sprintf("unable to get service %s: %s", backend.Service.Name, err.Error())
+			path.Backends = append(path.Backends, tb)
+			continue
+		}
+		// The route ends here. Its requests never reach the service's pods.
+		if !hasServicePort(svc, backend.Service.Port) {
+			tb.ErrorMsg = fmt.Sprintf("service %s has no port %s", svc.Name, servicePortString(backend.Service.Port))
+			path.Backends = append(path.Backends, tb)
+			continue
+		}
+
+		selector := labels.SelectorFromSet(map[string]string{discoveryv1.LabelServiceName: backend.Service.Name})
+		slices, err := discoveryclient.EndpointSlices(nsName).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			tb.ErrorMsg = fmt.Sprintf("unable to list endpointslices of service %s: %s", backend.Service.Name, err.Error())
+		} else {
+			tb.Pods = trafficPods(slices.Items)
+		}
+		path.Backends = append(path.Backends, tb)
+	}
+
+	return path, nil
+}
+
+// trafficPods merges the pods of every slice. A dual stack service has a slice per address family.
+func trafficPods(slices []discoveryv1.EndpointSlice) []TrafficPod {
+	byName := make(map[string]*TrafficPod)
+	for _, slice := range slices {
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.TargetRef == nil || endpoint.TargetRef.Kind != "Pod" {
+				continue
+			}
+
+			pod, found := byName[endpoint.TargetRef.Name]
+			if !found {
+				pod = &TrafficPod{
+					Name:      endpoint.TargetRef.Name,
+					Ready:     relations.IsEndpointReady(endpoint),
+					Addresses: make([]string, 0),
+				}
+				if endpoint.NodeName != nil {
+					pod.NodeName = *endpoint.NodeName
+				}
+				byName[pod.Name] = pod
+			}
+			pod.Addresses = append(pod.Addresses, endpoint.Addresses...)
+		}
+	}
+
+	pods := make([]TrafficPod, 0, len(byName))
+	for _, pod := range byName {
+		pods = append(pods, *pod)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+	return pods
+}
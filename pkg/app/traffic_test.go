@@ -0,0 +1,49 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+)
+
+func TestTrafficPods(t *testing.T) {
+	ready, notReady := true, false
+	node := "node-1"
+	endpoint := func(kind string, name string, address string, isReady *bool) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{
+			Addresses:  []string{address},
+			Conditions: discoveryv1.EndpointConditions{Ready: isReady},
+			NodeName:   &node,
+			TargetRef:  &corev1.ObjectReference{Kind: kind, Name: name},
+		}
+	}
+	// A dual stack service has a slice for each address family
+	slices := []discoveryv1.EndpointSlice{
+		{
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Endpoints: []discoveryv1.Endpoint{
+				endpoint("Pod", "product-b", "10.0.0.2", &notReady),
+				endpoint("Pod", "product-a", "10.0.0.1", &ready),
+				// ie a selectorless service's manually managed endpoints
+				{Addresses: []string{"192.168.0.1"}},
+			},
+		},
+		{
+			AddressType: discoveryv1.AddressTypeIPv6,
+			Endpoints: []discoveryv1.Endpoint{
+				endpoint("Pod", "product-a", "fd00::1", &ready),
+				endpoint("Node", "node-1", "fd00::9", nil),
+			},
+		},
+	}
+
+	expected := []TrafficPod{
+		{Name: "product-a", Ready: true, NodeName: "node-1", Addresses: []string{"10.0.0.1", "fd00::1"}},
+		{Name: "product-b", Ready: false, NodeName: "node-1", Addresses: []string{"10.0.0.2"}},
+	}
+	if actual := trafficPods(slices); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	util "github.com/cheriot/kubenav/internal/util"
+	"github.com/cheriot/kubenav/pkg/app/relations"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Bounds the walk in case of an ownerReference cycle. Built in controllers are at most three deep.
+const maxOwnershipDepth = 8
+
+type OwnershipNode struct {
+	GroupKind schema.GroupKind `json:"groupKind"`
+	Namespace string           `json:"namespace"`
+	Name      string           `json:"name"`
+	// The object the tree was requested for
+	Selected bool `json:"selected"`
+	// The object's row from the table printers. ie READY, UP-TO-DATE, AVAILABLE for a Deployment
+	Columns  []string         `json:"columns"`
+	Cells    []interface{}    `json:"cells"`
+	Children []*OwnershipNode `json:"children"`
+	ErrorMsg string           `json:"error"`
+}
+
+// OwnershipTree walks up ownerReferences to the top-most owner of an object and returns the tree of everything that
+// owner owns.
+func (kc *KubeCluster) OwnershipTree(ctx context.Context, nsName string, kind string, resourceName string) (*OwnershipNode, error) {
+	matches := findAPIResources(kc.apiResources, kind)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("unable to find an api resource: %s", kind)
+	}
+
+	selected, err := kc.getResource(ctx, matches[0], nsName, resourceName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get %s %s/%s: %w", kind, nsName, resourceName, err)
+	}
+
+	// ancestry[0] is the top-most owner, ancestry[len-1] is the selected object
+	ancestry := []childObject{{apiResource: matches[0], obj: selected}}
+	for len(ancestry) < maxOwnershipDepth {
+		owner, found := kc.getOwner(ctx, ancestry[0].obj)
+		if !found {
+			break
+		}
+		ancestry = append([]childObject{owner}, ancestry...)
+	}
+
+	return kc.ownershipNode(ctx, ancestry[0], ancestry[1:], selected.GetUID(), 0, make(childListCache)), nil
+}
+
+// getOwner fetches the controller, or else the first owner, of obj.
+func (kc *KubeCluster) getOwner(ctx context.Context, obj *unstructured.Unstructured) (childObject, bool) {
+	owners := relations.OwnerList(obj)
+	if len(owners) == 0 {
+		return childObject{}, false
+	}
+
+	owner := owners[0]
+	apiResource, found := findAPIResourceByGK(kc.apiResources, owner.GroupKind)
+	if !found {
+		log.Warnf("no api resource for owner %+v of %s", owner, obj.GetName())
+		return childObject{}, false
+	}
+
+	ownerObj, err := kc.getResource(ctx, apiResource, owner.Namespace, owner.Name)
+	if err != nil {
+		// ie the owner was deleted and garbage collection hasn't caught up
+		log.Warnf("unable to get owner %+v of %s: %v", owner, obj.GetName(), err)
+		return childObject{}, false
+	}
+	return childObject{apiResource: apiResource, obj: ownerObj}, true
+}
+
+// ownershipNode builds the subtree of co. path is the chain of descendants leading to the selected object. They're
+// always included, even when ChildKinds wouldn't look for them. ie a static pod owned by a Node.
+func (kc *KubeCluster) ownershipNode(ctx context.Context, co childObject, path []childObject, selectedUID types.UID, depth int, cache childListCache) *OwnershipNode {
+	node := &OwnershipNode{
+		GroupKind: toGK(co.apiResource),
+		Namespace: co.obj.GetNamespace(),
+		Name:      co.obj.GetName(),
+		Selected:  co.obj.GetUID() == selectedUID,
+		Columns:   make([]string, 0),
+		Cells:     make([]interface{}, 0),
+		Children:  make([]*OwnershipNode, 0),
+	}
+
+	table, err := PrintObject(kc.scheme, co.apiResource, co.obj)
+	if err != nil {
+		node.ErrorMsg = err.Error()
+	} else if len(table.Rows) > 0 {
+		for _, cd := range table.ColumnDefinitions {
+			node.Columns = append(node.Columns, cd.Name)
+		}
+		node.Cells = table.Rows[0].Cells
+	}
+
+	if depth >= maxOwnershipDepth {
+		return node
+	}
+
+	children, errors := kc.listChildren(ctx, co.obj, node.GroupKind, cache)
+	if len(errors) > 0 {
+		msgs := util.Map(errors, func(err error) string { return err.Error() })
+		if node.ErrorMsg != "" {
+			msgs = append([]string{node.ErrorMsg}, msgs...)
+		}
+		node.ErrorMsg = strings.Join(msgs, "; ")
+	}
+
+	if len(path) > 0 {
+		onPath := false
+		for _, child := range children {
+			onPath = onPath || child.obj.GetUID() == path[0].obj.GetUID()
+		}
+		if !onPath {
+			children = append(children, path[0])
+		}
+	}
+
+	for _, child := range children {
+		var childPath []childObject
+		if len(path) > 0 && child.obj.GetUID() == path[0].obj.GetUID() {
+			childPath = path[1:]
+		}
+		node.Children = append(node.Children, kc.ownershipNode(ctx, child, childPath, selectedUID, depth+1, cache))
+	}
+
+	return node
+}
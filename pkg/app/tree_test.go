@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func controlledBy(kind string, apiVersion string, name string, uid string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{APIVersion: apiVersion, Kind: kind, Name: name, UID: types.UID(uid), Controller: &controller}}
+}
+
+func deploymentObjects() []runtime.Object {
+	meta := func(name string, uid string, owners []metav1.OwnerReference) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: "back-end", UID: types.UID(uid), OwnerReferences: owners}
+	}
+	rsMeta := metav1.TypeMeta{APIVersion: "apps/v1", Kind: "ReplicaSet"}
+	podMeta := metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}
+	// The printers expect the api server's defaults
+	replicas := int32(2)
+	return []runtime.Object{
+		&appsv1.Deployment{TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"}, ObjectMeta: meta("product", "deploy-uid", nil), Spec: appsv1.DeploymentSpec{Replicas: &replicas}},
+		&appsv1.ReplicaSet{TypeMeta: rsMeta, ObjectMeta: meta("product-old", "rs-old-uid", controlledBy("Deployment", "apps/v1", "product", "deploy-uid")), Spec: appsv1.ReplicaSetSpec{Replicas: &replicas}},
+		&appsv1.ReplicaSet{TypeMeta: rsMeta, ObjectMeta: meta("product-new", "rs-new-uid", controlledBy("Deployment", "apps/v1", "product", "deploy-uid")), Spec: appsv1.ReplicaSetSpec{Replicas: &replicas}},
+		&corev1.Pod{TypeMeta: podMeta, ObjectMeta: meta("product-new-a", "pod-a-uid", controlledBy("ReplicaSet", "apps/v1", "product-new", "rs-new-uid"))},
+		&corev1.Pod{TypeMeta: podMeta, ObjectMeta: meta("product-new-b", "pod-b-uid", controlledBy("ReplicaSet", "apps/v1", "product-new", "rs-new-uid"))},
+		&corev1.Pod{TypeMeta: podMeta, ObjectMeta: meta("cart-a", "cart-uid", nil)},
+	}
+}
+
+func TestOwnershipTree(t *testing.T) {
+	kc, client := fakeCluster(t, deploymentObjects()...)
+
+	root, err := kc.OwnershipTree(context.Background(), "back-end", "pod", "product-new-a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if root.Name != "product" || root.GroupKind.Kind != "Deployment" || root.ErrorMsg != "" {
+		t.Fatalf("expected the deployment at the root, got %+v", root)
+	}
+	children := make(map[string]*OwnershipNode)
+	for _, rs := range root.Children {
+		children[rs.Name] = rs
+	}
+	if len(children) != 2 || len(children["product-old"].Children) != 0 || len(children["product-new"].Children) != 2 {
+		t.Fatalf("expected two replica sets and the new one's two pods, got %+v", root.Children)
+	}
+	for _, pod := range children["product-new"].Children {
+		if pod.Selected != (pod.Name == "product-new-a") {
+			t.Errorf("unexpected selected %v for %s", pod.Selected, pod.Name)
+		}
+	}
+
+	// Both replica sets look for pods in back-end
+	podLists := 0
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "list" && action.GetResource().Resource == "pods" {
+			podLists++
+		}
+	}
+	if podLists != 1 {
+		t.Errorf("expected pods to be listed once, got %d", podLists)
+	}
+}
+
+func TestOwnershipTreeChildListErrors(t *testing.T) {
+	kc, client := fakeCluster(t, deploymentObjects()...)
+	client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+
+	root, err := kc.OwnershipTree(context.Background(), "back-end", "deployment", "product")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, rs := range root.Children {
+		if !strings.Contains(rs.ErrorMsg, "unable to list Pod children of "+rs.Name) || !strings.Contains(rs.ErrorMsg, "forbidden") {
+			t.Errorf("expected the pod list error on %s, got '%s'", rs.Name, rs.ErrorMsg)
+		}
+	}
+}